@@ -8,4 +8,4 @@ require (
 	github.com/zclconf/go-cty v1.10.0
 )
 
-go 1.13
+go 1.16
@@ -1,10 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/IBM-Cloud/terraform-config-inspect/tfconfig"
 	flag "github.com/spf13/pflag"
@@ -14,36 +25,134 @@ var showJSON = flag.Bool("json", false, "produce JSON-formatted output")
 
 var metadataJsonFile = flag.String("metadata", "", "Provider metadata json file path")
 var showVariables = flag.Bool("filter-variables", false, "produce JSON-formatted output for variables")
+var showTfvars = flag.Bool("tfvars", false, "produce a terraform.tfvars skeleton (terraform.tfvars.json with --json)")
+var showJSONSchema = flag.Bool("json-schema", false, "produce a JSON Schema document describing the module's input variables")
+var showCatalog = flag.Bool("catalog", false, "produce a catalog manifest (ibm_catalog.json) describing the module")
+var catalogMergeFile = flag.String("catalog-merge", "", "update the given catalog manifest file in place, reporting drift")
+var catalogFlavor = flag.String("catalog-flavor", "", "name of the catalog manifest flavor to update with --catalog-merge")
+var injectFile = flag.String("inject", "", "replace the region between the begin and end markers of the given file with the markdown output")
+var injectCheck = flag.Bool("check", false, "with --inject, don't write the file but exit with status 1 if it is out of date")
+var beginMarker = flag.String("begin-marker", tfconfig.DefaultBeginMarker, "marker for the start of the region replaced by --inject")
+var endMarker = flag.String("end-marker", tfconfig.DefaultEndMarker, "marker for the end of the region replaced by --inject")
+var templateFile = flag.String("template", "", "render markdown output using the Go template in the given file")
+var markdownStyle = flag.String("markdown-style", "list", "style of markdown output: list or table")
+var sortBy = flag.String("sort-by", "name", "order of rows in table-style markdown output: name, required or position")
+var htmlDir = flag.String("html", "", "write a static HTML documentation site for the module and its children into the given directory")
+var htmlSourceURL = flag.String("html-source-url", "", "base URL for linking definitions in the HTML site to their source files")
+var enableRules = flag.StringSlice("enable-rule", nil, "with lint, run only the given rules")
+var disableRules = flag.StringSlice("disable-rule", nil, "with lint, don't run the given rules")
+var ruleFiles = flag.StringSlice("rules", nil, "with lint, also check the rules declared in the given rule files")
+var lintRecursive = flag.Bool("recursive", false, "with lint, also check the child modules that the module calls")
+var diagFormat = flag.String("format", "", "write only the problems found in the module, by lint or by scan, in the given format: text, human, json, sarif or junit")
+var tfvarsFile = flag.String("tfvars-file", "", "with lint, also validate the variable values in the given .tfvars or .tfvars.json file")
+var revs = flag.StringArray("rev", nil, "read the module as of the given git revision of its repository; with diff, give once to compare with the working tree or twice to compare two revisions")
+var scanExclude = flag.StringSlice("exclude", nil, "with scan, also skip the directories matching the given glob patterns")
+var scanJSONLines = flag.Bool("jsonl", false, "with scan, write a JSON Lines stream with a line for each module instead of a single JSON document")
+var parallelism = flag.Int("parallelism", 0, "with scan, lint --recursive, --html and serve, the largest number of files to load at once (default: the number of CPUs)")
+var loadTimeout = flag.Duration("timeout", 0, "with scan, lint --recursive and --html, stop loading modules after the given duration, such as 30s; with serve, the longest a request may take (default 30s)")
+var cacheDir = flag.String("cache-dir", "", "reuse the modules loaded by earlier runs from the same files, keeping them in the given directory")
+var watchMode = flag.Bool("watch", false, "keep running, producing the output again whenever the module's files, or the files given with other flags, change, as reported by inotify on Linux or found by polling elsewhere")
+var outputFile = flag.String("output", "", "write the output to the given file instead of stdout")
+var listenAddr = flag.String("listen", "localhost:8080", "with serve, the address to listen on")
+var serveRoot = flag.String("root", "", "with serve, the directory that module paths in requests are relative to (default: modules must be uploaded)")
+var serveMetadata = flag.StringToString("serve-metadata", nil, "with serve, the provider metadata files that requests to /enrich, /lint and /validate-tfvars may choose by name, as NAME=FILE")
+var maxRequestSize = flag.Int64("max-request-size", 0, "with serve, the largest request body accepted, in bytes (default 10 MiB)")
+var graphFormat = flag.String("graph", "", "produce a graph of the references between the module's objects: dot or mermaid")
 
 // This function expects users to pass template path else it takes current path ./
 func main() {
 	flag.Parse()
 
+	if *watchMode {
+		watchAndRerun()
+		return
+	}
+	if *outputFile != "" {
+		f, err := os.Create(*outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error creating output file: %s\n", err)
+			os.Exit(2)
+		}
+		// Everything below writes to os.Stdout.
+		os.Stdout = f
+	}
+
+	if flag.Arg(0) == "diff" {
+		if flag.NArg() != 3 && (flag.NArg() != 2 || len(*revs) == 0) {
+			fmt.Fprintln(os.Stderr, "usage: terraform-config-inspect diff OLD NEW, or diff --rev REV DIR")
+			os.Exit(2)
+		}
+		showModuleDiff(flag.Arg(1), flag.Arg(flag.NArg()-1), *revs, *showJSON)
+		return
+	}
+
+	if flag.NArg() > 0 && flag.NArg() <= 2 && flag.Arg(0) == "lint" {
+		dir := "."
+		if flag.NArg() == 2 {
+			dir = flag.Arg(1)
+		}
+		lintModule(dir, *showJSON)
+		return
+	}
+
+	if flag.NArg() == 1 && flag.Arg(0) == "serve" {
+		serveAPI()
+		return
+	}
+
+	if flag.NArg() > 0 && flag.NArg() <= 2 && flag.Arg(0) == "scan" {
+		root := "."
+		if flag.NArg() == 2 {
+			root = flag.Arg(1)
+		}
+		scanModules(root, *scanJSONLines)
+		return
+	}
+
 	var dir string
 	if flag.NArg() > 0 {
 		dir = flag.Arg(0)
 	} else {
 		dir = "."
 	}
+	if *htmlDir != "" {
+		writeModuleHTML(dir, *htmlDir)
+		return
+	}
+
 	// If --metadata flag is provided, it parses through provider metdata file and extracts additional details of a given variable.
 	// else it ll parse and fetch just the terraform template config.
+	fsys, moduleDir := openModule(dir, singleRev())
 	var module *tfconfig.Module
 	if *metadataJsonFile != "" {
-		var err tfconfig.Diagnostics
-		module, err = tfconfig.CheckForInitDirectoryAndLoadIBMModule(dir, *metadataJsonFile)
-		if err != nil {
-			err = append(err, tfconfig.Diagnostic{
-				Severity: tfconfig.DiagError,
-				Summary:  "loadErr",
-				Detail:   fmt.Sprintf("%s", err),
-			})
-			log.Fatal(err)
-		}
+		module, _ = loadEnrichedModule(fsys, moduleDir)
+	} else if *templateFile != "" {
+		// Templates may describe the contents of child modules, which are
+		// only known once those modules are loaded too.
+		ctx, cancel, opts := loadContext()
+		tree, _ := tfconfig.LoadModuleTreeContext(ctx, fsys, moduleDir, opts)
+		cancel()
+		tree.AddChildContents()
+		module = tree.Module
 	} else {
-		module, _ = tfconfig.LoadModule(dir)
+		module, _ = loadModule(fsys, moduleDir)
 	}
 
-	if *showJSON {
+	if *diagFormat != "" {
+		writeDiagnostics(module.Diagnostics, fsys, []string{moduleDir}, []string{tfconfig.SARIFDefaultRuleID}, *diagFormat)
+	} else if *graphFormat != "" {
+		showModuleGraph(module, *graphFormat)
+	} else if *showTfvars {
+		showModuleTfvars(module, *showJSON)
+	} else if *showJSONSchema {
+		showModuleJSONSchema(module)
+	} else if *injectFile != "" {
+		injectModuleMarkdown(module, *showVariables, *injectFile, *injectCheck)
+	} else if *catalogMergeFile != "" {
+		mergeCatalogManifest(module, *catalogMergeFile, *catalogFlavor)
+	} else if *showCatalog {
+		showModuleCatalog(module)
+	} else if *showJSON {
 		showModuleJSON(module, *showVariables)
 	} else {
 		showModuleMarkdown(module, *showVariables)
@@ -102,9 +211,632 @@ func showModuleJSON(module *tfconfig.Module, variable bool) {
 }
 
 func showModuleMarkdown(module *tfconfig.Module, variable bool) {
-	err := tfconfig.RenderMarkdown(os.Stdout, module, variable)
+	err := renderModuleMarkdown(os.Stdout, module, variable)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error rendering template: %s\n", err)
 		os.Exit(2)
 	}
 }
+
+func showModuleTfvars(module *tfconfig.Module, asJSON bool) {
+	var err error
+	if asJSON {
+		err = tfconfig.RenderTfvarsJSON(os.Stdout, module)
+	} else {
+		err = tfconfig.RenderTfvars(os.Stdout, module)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error rendering tfvars: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+func showModuleJSONSchema(module *tfconfig.Module) {
+	err := tfconfig.RenderJSONSchema(os.Stdout, module)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error producing JSON Schema: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+func showModuleGraph(module *tfconfig.Module, format string) {
+	g := tfconfig.NewGraph(module)
+	var err error
+	switch format {
+	case "dot":
+		err = tfconfig.RenderGraphDOT(os.Stdout, g)
+	case "mermaid":
+		err = tfconfig.RenderGraphMermaid(os.Stdout, g)
+	default:
+		err = fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error producing graph: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+// showModuleDiff compares the modules in the two given directories or
+// archives, which may be the same directory. The old module is read as of
+// the first of the given git revisions, if any, and the new module as of the
+// second, if any.
+func showModuleDiff(oldPath, newPath string, revs []string, asJSON bool) {
+	if len(revs) > 2 {
+		fmt.Fprintln(os.Stderr, "error: --rev may be given at most twice with diff")
+		os.Exit(2)
+	}
+	var oldRev, newRev string
+	if len(revs) > 0 {
+		oldRev = revs[0]
+	}
+	if len(revs) > 1 {
+		newRev = revs[1]
+	}
+	oldModule, oldDiags := loadModule(openModule(oldPath, oldRev))
+	newModule, newDiags := loadModule(openModule(newPath, newRev))
+	if diags := append(oldDiags, newDiags...); diags.HasErrors() {
+		fmt.Fprintf(os.Stderr, "error loading modules: %s\n", diags)
+		os.Exit(1)
+	}
+
+	d := tfconfig.Diff(oldModule, newModule)
+	var err error
+	if asJSON {
+		err = tfconfig.RenderDiffJSON(os.Stdout, d)
+	} else {
+		err = tfconfig.RenderDiffMarkdown(os.Stdout, d)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error producing diff: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+// scanModules loads every module under the given root directory, other than
+// those excluded by default or with --exclude, and writes them keyed by
+// their relative paths, with a summary of their diagnostics. It exits with
+// status 1 if any module has errors.
+//
+// With --format, it writes only the problems found instead, those of each
+// module along with those found while scanning. JUnit output has a suite for
+// the root directory, holding the problems found while scanning, and one for
+// each other module directory.
+func scanModules(root string, jsonLines bool) {
+	ctx, cancel, opts := loadContext()
+	defer cancel()
+	fsys := tfconfig.NewOsFs()
+	report := tfconfig.ScanContext(ctx, fsys, root, &tfconfig.ScanOptions{
+		LoadOptions: *opts,
+		Exclude:     append(append([]string(nil), tfconfig.DefaultScanExclude...), *scanExclude...),
+	})
+
+	if *diagFormat != "" {
+		paths := make([]string, 0, len(report.Modules))
+		for path := range report.Modules {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		dirs := []string{root}
+		diags := report.Diagnostics
+		for _, path := range paths {
+			if path != "." {
+				dirs = append(dirs, filepath.Join(root, filepath.FromSlash(path)))
+			}
+			diags = append(diags, report.Modules[path].Module.Diagnostics...)
+		}
+		writeDiagnostics(diags, fsys, dirs, []string{tfconfig.SARIFDefaultRuleID}, *diagFormat)
+		if report.HasErrors() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(report.Diagnostics) != 0 {
+		tfconfig.RenderDiagnostics(os.Stderr, report.Diagnostics)
+	}
+
+	var err error
+	if jsonLines {
+		err = tfconfig.RenderScanJSONLines(os.Stdout, report)
+	} else {
+		err = tfconfig.RenderScanJSON(os.Stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error producing scan report: %s\n", err)
+		os.Exit(2)
+	}
+	if report.HasErrors() {
+		os.Exit(1)
+	}
+}
+
+// serveAPI serves the HTTP API of tfconfig.NewServer until interrupted.
+// Modules are cached in memory, or in the directory given with --cache-dir.
+func serveAPI() {
+	var rules []tfconfig.Rule
+	for _, filename := range *ruleFiles {
+		fileRules, diags := tfconfig.LoadRuleFile(filename)
+		if diags.HasErrors() {
+			fmt.Fprintf(os.Stderr, "error loading rules: %s\n", diags)
+			os.Exit(2)
+		}
+		rules = append(rules, fileRules...)
+	}
+	cache := moduleCache()
+	if cache == nil {
+		cache = tfconfig.NewCache(tfconfig.NewMemoryCacheStore(1000))
+	}
+	timeout := *loadTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	srv := &http.Server{
+		Addr: *listenAddr,
+		Handler: tfconfig.NewServer(&tfconfig.ServerOptions{
+			Root:           *serveRoot,
+			Metadata:       *serveMetadata,
+			Rules:          rules,
+			MaxRequestSize: *maxRequestSize,
+			Timeout:        timeout,
+			LoadOptions:    tfconfig.LoadOptions{Parallelism: *parallelism, Cache: cache},
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       timeout,
+		WriteTimeout:      2 * timeout,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("[INFO] Serving on %s", *listenAddr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		fmt.Fprintf(os.Stderr, "error serving: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+// lintModule reports the problems found by the built-in lint rules and
+// those in the files given with --rules, as selected with --enable-rule and
+// --disable-rule, along with any found while loading the module, and exits
+// with status 1 if there are any.
+func lintModule(path string, asJSON bool) {
+	fsys, dir := openModule(path, singleRev())
+	rules := append([]tfconfig.Rule(nil), tfconfig.LintRules...)
+	for _, filename := range *ruleFiles {
+		fileRules, diags := tfconfig.LoadRuleFile(filename)
+		if diags.HasErrors() {
+			fmt.Fprintf(os.Stderr, "error loading rules: %s\n", diags)
+			os.Exit(2)
+		}
+		rules = append(rules, fileRules...)
+	}
+	rules, err := tfconfig.SelectRules(rules, *enableRules, *disableRules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error selecting lint rules: %s\n", err)
+		os.Exit(2)
+	}
+
+	var checks []string
+	for _, rule := range rules {
+		checks = append(checks, rule.ID())
+	}
+	checks = append(checks, tfconfig.SARIFDefaultRuleID)
+
+	var diags tfconfig.Diagnostics
+	var module *tfconfig.Module
+	dirs := []string{dir}
+	if *lintRecursive {
+		var tree *tfconfig.ModuleTree
+		ctx, cancel, opts := loadContext()
+		defer cancel()
+		tree, diags = tfconfig.LoadModuleTreeContext(ctx, fsys, dir, opts)
+		if *metadataJsonFile != "" {
+			enrichModuleTree(fsys, tree)
+		}
+		diags = append(diags, tfconfig.LintTree(tree, rules)...)
+		module = tree.Module
+		for _, child := range tree.Children {
+			child.Walk(func(t *tfconfig.ModuleTree) error {
+				dirs = append(dirs, t.Module.Path)
+				return nil
+			})
+		}
+	} else if *metadataJsonFile != "" {
+		// Rules may check the metadata, such as the cloud data types of
+		// variables.
+		module, diags = loadEnrichedModule(fsys, dir)
+		diags = append(diags, tfconfig.Lint(module, rules)...)
+	} else {
+		module, diags = loadModule(fsys, dir)
+		diags = append(diags, tfconfig.Lint(module, rules)...)
+	}
+
+	if *tfvarsFile != "" {
+		src, err := ioutil.ReadFile(*tfvarsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading %s: %s\n", *tfvarsFile, err)
+			os.Exit(2)
+		}
+		diags = append(diags, tfconfig.ValidateTfvars(module, src, *tfvarsFile)...)
+		checks = append(checks, tfconfig.TfvarsRuleID)
+	}
+
+	format := *diagFormat
+	if asJSON {
+		format = "json"
+	}
+	writeDiagnostics(diags, fsys, dirs, checks, format)
+
+	if len(diags) > 0 {
+		os.Exit(1)
+	}
+}
+
+// enrichModuleTree replaces each module in the given tree with the module
+// enriched with the provider metadata in the file given with --metadata.
+// The problems found while loading the tree have already been reported, so
+// only those with the metadata itself are.
+func enrichModuleTree(fsys tfconfig.FS, tree *tfconfig.ModuleTree) {
+	root, _ := loadEnrichedModule(fsys, tree.Module.Path)
+	metadata, _ := tfconfig.LoadProviderMetadataFile(tfconfig.NewOsFs(), *metadataJsonFile)
+	tree.Walk(func(t *tfconfig.ModuleTree) error {
+		if t == tree {
+			t.Module = root
+		} else if module, diags := tfconfig.LoadIBMModuleFromFilesystem(fsys, t.Module.Path, metadata, nil); !diags.HasErrors() {
+			t.Module = module
+		}
+		return nil
+	})
+}
+
+// writeDiagnostics writes the given diagnostics in the given format: text,
+// human, json, sarif or junit. Human output includes source snippets read
+// from the given FS. Paths in SARIF output are made relative to the first of
+// the given module directories. JUnit output has a suite for each module
+// directory, with a passing test case for each of the given checks that
+// found no problems.
+func writeDiagnostics(diags tfconfig.Diagnostics, fsys tfconfig.FS, dirs []string, checks []string, format string) {
+	switch format {
+	case "", "text":
+		for _, diag := range diags {
+			severity := "warning"
+			if diag.Severity == tfconfig.DiagError {
+				severity = "error"
+			}
+			if diag.Pos != nil {
+				fmt.Printf("%s:%d: ", diag.Pos.Filename, diag.Pos.Line)
+			}
+			fmt.Printf("%s: %s", severity, diag.Summary)
+			if diag.Detail != "" {
+				fmt.Printf(": %s", diag.Detail)
+			}
+			if diag.Module != "" {
+				fmt.Printf(" (in %s)", diag.Module)
+			}
+			if diag.Rule != "" {
+				fmt.Printf(" [%s]", diag.Rule)
+			}
+			fmt.Println()
+		}
+	case "json":
+		if diags == nil {
+			diags = tfconfig.Diagnostics{}
+		}
+		j, err := json.MarshalIndent(diags, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error producing JSON: %s\n", err)
+			os.Exit(2)
+		}
+		os.Stdout.Write(j)
+		os.Stdout.Write([]byte{'\n'})
+	case "sarif":
+		if err := tfconfig.RenderSARIF(os.Stdout, diags, dirs[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "error producing SARIF: %s\n", err)
+			os.Exit(2)
+		}
+	case "human":
+		if err := tfconfig.RenderDiagnostics(os.Stdout, diags.WithSnippets(fsys)); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing diagnostics: %s\n", err)
+			os.Exit(2)
+		}
+	case "junit":
+		if err := tfconfig.RenderJUnit(os.Stdout, tfconfig.NewJUnitSuites(dirs, checks, diags)); err != nil {
+			fmt.Fprintf(os.Stderr, "error producing JUnit XML: %s\n", err)
+			os.Exit(2)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported format %q\n", format)
+		os.Exit(2)
+	}
+}
+
+func showModuleCatalog(module *tfconfig.Module) {
+	err := tfconfig.RenderCatalogManifest(os.Stdout, module)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error producing catalog manifest: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+func mergeCatalogManifest(module *tfconfig.Module, filename, flavor string) {
+	existing, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading catalog manifest: %s\n", err)
+		os.Exit(2)
+	}
+	merged, drift, err := tfconfig.MergeCatalogManifest(existing, module, flavor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error merging catalog manifest: %s\n", err)
+		os.Exit(2)
+	}
+	for _, d := range drift {
+		fmt.Fprintf(os.Stderr, "drift: %s\n", d)
+	}
+	err = ioutil.WriteFile(filename, merged, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing catalog manifest: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+func injectModuleMarkdown(module *tfconfig.Module, variable bool, filename string, check bool) {
+	var buf bytes.Buffer
+	err := renderModuleMarkdown(&buf, module, variable)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error rendering template: %s\n", err)
+		os.Exit(2)
+	}
+	existing, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading %s: %s\n", filename, err)
+		os.Exit(2)
+	}
+	updated, err := tfconfig.InjectBetweenMarkers(existing, buf.Bytes(), *beginMarker, *endMarker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error injecting into %s: %s\n", filename, err)
+		os.Exit(2)
+	}
+	if check {
+		if !bytes.Equal(existing, updated) {
+			fmt.Fprintf(os.Stderr, "%s is out of date; run without --check to update it\n", filename)
+			os.Exit(1)
+		}
+		return
+	}
+	if bytes.Equal(existing, updated) {
+		return
+	}
+	err = ioutil.WriteFile(filename, updated, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing %s: %s\n", filename, err)
+		os.Exit(2)
+	}
+}
+
+// renderModuleMarkdown renders the module using the template given with
+// --template, or the built-in markdown style selected by --markdown-style
+// otherwise.
+func renderModuleMarkdown(w io.Writer, module *tfconfig.Module, variable bool) error {
+	if *templateFile != "" {
+		tmplText, err := ioutil.ReadFile(*templateFile)
+		if err != nil {
+			return err
+		}
+		return tfconfig.RenderWithTemplate(w, module, string(tmplText))
+	}
+	switch *markdownStyle {
+	case "list":
+		return tfconfig.RenderMarkdown(w, module, variable)
+	case "table":
+		return tfconfig.RenderMarkdownTable(w, module, tfconfig.MarkdownTableOptions{
+			SortBy: tfconfig.MarkdownSortOrder(*sortBy),
+		})
+	default:
+		return fmt.Errorf("unsupported markdown style %q", *markdownStyle)
+	}
+}
+
+func writeModuleHTML(path, outDir string) {
+	ctx, cancel, opts := loadContext()
+	defer cancel()
+	fsys, dir := openModule(path, singleRev())
+	tree, diags := tfconfig.LoadModuleTreeContext(ctx, fsys, dir, opts)
+	for _, diag := range diags {
+		if diag.Severity == tfconfig.DiagWarning {
+			log.Printf("[WARN] %s: %s", diag.Summary, diag.Detail)
+		}
+	}
+	err := tfconfig.WriteHTMLSite(outDir, tree, tfconfig.HTMLOptions{
+		SourceURL: *htmlSourceURL,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing HTML site: %s\n", err)
+		os.Exit(2)
+	}
+	if diags.HasErrors() {
+		os.Exit(1)
+	}
+}
+
+// openModule returns the FS and directory to load the module at the given
+// path from: the path itself, the contents of the zip or tar.gz archive it
+// names, less any "//subdir" selector, or the path as of the given git
+// revision if it isn't blank.
+func openModule(path, rev string) (tfconfig.FS, string) {
+	if rev != "" {
+		if tfconfig.IsArchivePath(path) {
+			fmt.Fprintln(os.Stderr, "error: --rev cannot be used with module archives")
+			os.Exit(2)
+		}
+		fsys, dir, err := tfconfig.OpenGitRevision(path, rev)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading module at revision %s: %s\n", rev, err)
+			os.Exit(2)
+		}
+		return fsys, dir
+	}
+	if !tfconfig.IsArchivePath(path) {
+		return tfconfig.NewOsFs(), path
+	}
+	fsys, dir, err := tfconfig.OpenArchive(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading module archive: %s\n", err)
+		os.Exit(2)
+	}
+	return fsys, dir
+}
+
+// loadContext returns a context for loading many modules, which is canceled
+// on an interrupt or once the --timeout has passed, along with the options
+// given with --parallelism.
+func loadContext() (context.Context, context.CancelFunc, *tfconfig.LoadOptions) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	cancel := stop
+	if *loadTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, *loadTimeout)
+		cancel = func() {
+			cancelTimeout()
+			stop()
+		}
+	}
+	return ctx, cancel, &tfconfig.LoadOptions{Parallelism: *parallelism, Cache: moduleCache()}
+}
+
+// loadEnrichedModule loads the module in the given directory of the given
+// FS, enriched with the provider metadata in the file given with --metadata,
+// using the cache given with --cache-dir if any. It exits if the metadata
+// or the module can't be read, such as when the module hasn't been
+// initialized with "terraform init".
+func loadEnrichedModule(fsys tfconfig.FS, dir string) (*tfconfig.Module, tfconfig.Diagnostics) {
+	metadata, diags := tfconfig.LoadProviderMetadataFile(tfconfig.NewOsFs(), *metadataJsonFile)
+	var module *tfconfig.Module
+	var moduleDiags tfconfig.Diagnostics
+	if cache := moduleCache(); cache != nil {
+		module, moduleDiags = cache.CheckForInitDirectoryAndLoadIBMModule(fsys, dir, metadata)
+	} else {
+		module, moduleDiags = tfconfig.CheckForInitDirectoryAndLoadIBMModuleFromFilesystem(fsys, dir, metadata)
+	}
+	diags = append(diags, moduleDiags...)
+	if module == nil {
+		tfconfig.RenderDiagnostics(os.Stderr, diags)
+		os.Exit(1)
+	}
+	module.Diagnostics = diags
+	return module, diags
+}
+
+// loadModule loads the module in the given directory of the given FS, using
+// the cache given with --cache-dir if any.
+func loadModule(fsys tfconfig.FS, dir string) (*tfconfig.Module, tfconfig.Diagnostics) {
+	if cache := moduleCache(); cache != nil {
+		return cache.LoadModule(fsys, dir)
+	}
+	return tfconfig.LoadModuleFromFilesystem(fsys, dir)
+}
+
+// watchAndRerun runs this program again with the same arguments, other than
+// --watch, and then again whenever the files it reads change, until it is
+// interrupted. The runs share a cache, so that only the modules whose files
+// changed are loaded again.
+func watchAndRerun() {
+	if len(*revs) != 0 {
+		fmt.Fprintln(os.Stderr, "error: --watch cannot be used with --rev")
+		os.Exit(2)
+	}
+
+	var paths []string
+	switch {
+	case flag.Arg(0) == "diff":
+		paths = flag.Args()[1:]
+	case flag.Arg(0) == "lint" || flag.Arg(0) == "scan":
+		paths = flag.Args()[1:]
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+	case flag.NArg() > 0:
+		paths = flag.Args()[:1]
+	default:
+		paths = []string{"."}
+	}
+	for i, path := range paths {
+		// Archives are watched as a whole.
+		if sep := strings.Index(path, "//"); sep >= 0 && tfconfig.IsArchivePath(path[:sep]) {
+			paths[i] = path[:sep]
+		}
+	}
+	for _, filename := range append([]string{*metadataJsonFile, *tfvarsFile, *templateFile}, *ruleFiles...) {
+		if filename != "" {
+			paths = append(paths, filename)
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error finding executable: %s\n", err)
+		os.Exit(2)
+	}
+	args := append(os.Args[1:], "--watch=false")
+	if *cacheDir == "" {
+		dir, err := ioutil.TempDir("", "terraform-config-inspect-cache")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error creating cache: %s\n", err)
+			os.Exit(2)
+		}
+		defer os.RemoveAll(dir)
+		args = append(args, "--cache-dir="+dir)
+	}
+	run := func() {
+		cmd := exec.Command(exe, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		// A run that finds problems exits with a non-zero status, which
+		// doesn't stop the watch.
+		cmd.Run()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	run()
+	fmt.Fprintf(os.Stderr, "Watching %s for changes...\n", strings.Join(paths, ", "))
+	tfconfig.Watch(ctx, tfconfig.NewOsFs(), paths, nil, func(changed []string) error {
+		fmt.Fprintf(os.Stderr, "\nChanged %s\n\n", strings.Join(changed, ", "))
+		run()
+		return nil
+	})
+}
+
+// moduleCache returns the cache of modules kept in the directory given with
+// --cache-dir, or nil if there is none.
+func moduleCache() *tfconfig.Cache {
+	if *cacheDir == "" {
+		return nil
+	}
+	store, err := tfconfig.NewDiskCacheStore(*cacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening cache: %s\n", err)
+		os.Exit(2)
+	}
+	return tfconfig.NewCache(store)
+}
+
+// singleRev returns the git revision given with --rev, or a blank string if
+// there is none. Only diff accepts more than one.
+func singleRev() string {
+	switch len(*revs) {
+	case 0:
+		return ""
+	case 1:
+		return (*revs)[0]
+	default:
+		fmt.Fprintln(os.Stderr, "error: --rev may only be given more than once with diff")
+		os.Exit(2)
+		return ""
+	}
+}
@@ -0,0 +1,221 @@
+package tfconfig
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// archiveExts are the extensions of the archive formats understood by
+// OpenArchive.
+var archiveExts = []string{".zip", ".tar.gz", ".tgz"}
+
+// IsArchivePath reports whether the given path, less any "//subdir"
+// selector, names a zip or gzipped tar archive, judging by its extension:
+// ".zip", ".tar.gz" or ".tgz".
+func IsArchivePath(path string) bool {
+	archive, _ := splitArchivePath(path)
+	return archiveExt(archive) != ""
+}
+
+// OpenArchive opens the zip or gzipped tar archive named by the given path,
+// returning an FS that reads its contents along with the directory within
+// it that the path selects.
+//
+// The path may end with a "//subdir" selector naming a directory within the
+// archive, as module sources can, in which case the returned directory is
+// that subdirectory. Otherwise it is ".", the root of the archive. Archives
+// that contain a .terraform/modules directory, as written by "terraform
+// init", can be loaded with LoadModuleTreeFromFilesystem or
+// CheckForInitDirectoryAndLoadIBMModuleFromFilesystem to include the
+// modules it calls.
+func OpenArchive(path string) (FS, string, error) {
+	archive, subdir := splitArchivePath(path)
+	dir := "."
+	if subdir != "" {
+		dir = filepath.FromSlash(subdir)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var fsys FS
+	switch archiveExt(archive) {
+	case ".zip":
+		// A zip reader reads from the file as needed, so we read the whole
+		// archive now so that the file can be closed.
+		src, err := ioutil.ReadAll(f)
+		if err != nil {
+			return nil, "", err
+		}
+		fsys, err = NewZipFS(bytes.NewReader(src), int64(len(src)))
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %s", archive, err)
+		}
+	case ".tar.gz", ".tgz":
+		fsys, err = NewTarGzFS(f)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %s", archive, err)
+		}
+	default:
+		return nil, "", fmt.Errorf("%s is not a zip or tar.gz archive", archive)
+	}
+	return fsys, dir, nil
+}
+
+// LoadModuleFromArchive is a variant of LoadModule that reads the module
+// from the zip or gzipped tar archive named by the given path, which may end
+// with a "//subdir" selector as for OpenArchive. Source positions are
+// relative to the root of the archive.
+func LoadModuleFromArchive(path string) (*Module, Diagnostics) {
+	fsys, dir, err := OpenArchive(path)
+	if err != nil {
+		module := NewModule(path)
+		diags := Diagnostics{
+			{
+				Severity: DiagError,
+				Summary:  "Failed to read module archive",
+				Code:     DiagCodeReadArchive,
+				Detail:   fmt.Sprintf("Module archive %s cannot be read: %s.", path, err),
+			},
+		}
+		module.init(diags)
+		return module, diags
+	}
+	return LoadModuleFromFilesystem(fsys, dir)
+}
+
+// NewZipFS returns an FS that reads the contents of the zip archive read
+// from the given reader, which has the given size.
+func NewZipFS(r io.ReaderAt, size int64) (FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return FromIOFS(zr), nil
+}
+
+// NewTarGzFS returns an FS that reads the contents of the gzipped tar
+// archive read from the given reader. The whole archive is read into memory.
+// Entries other than regular files and directories, such as symbolic links,
+// are ignored.
+func NewTarGzFS(r io.Reader) (FS, error) {
+	return newTarGzFS(r, 0)
+}
+
+// newTarGzFS is NewTarGzFS, failing if the files in the archive would take
+// more than the given number of bytes, unless it is zero.
+func newTarGzFS(r io.Reader, maxSize int64) (FS, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	fsys := newMemFs()
+	tr := tar.NewReader(zr)
+	var size int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid path %q in archive", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			fsys.addDir(name, hdr.FileInfo().Mode(), hdr.ModTime)
+		case tar.TypeReg, tar.TypeRegA:
+			size += hdr.Size
+			if maxSize > 0 && size > maxSize {
+				return nil, errArchiveTooLarge
+			}
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			fsys.addFile(name, data, hdr.FileInfo().Mode(), hdr.ModTime)
+		}
+	}
+	return FromIOFS(fsys), nil
+}
+
+// errArchiveTooLarge is returned when the files in an archive are larger in
+// total than allowed.
+var errArchiveTooLarge = errors.New("archive contents are too large")
+
+// readArchive returns an FS that reads the contents of the given zip or
+// gzipped tar archive, judging its format by its first bytes rather than by
+// its name. It fails if the files in the archive would take more than the
+// given number of bytes, unless it is zero.
+func readArchive(src []byte, maxSize int64) (FS, error) {
+	switch {
+	case bytes.HasPrefix(src, []byte("PK\x03\x04")), bytes.HasPrefix(src, []byte("PK\x05\x06")):
+		zr, err := zip.NewReader(bytes.NewReader(src), int64(len(src)))
+		if err != nil {
+			return nil, err
+		}
+		// The zip reader checks that each file is no larger than its
+		// header says when it is read.
+		var size uint64
+		for _, f := range zr.File {
+			size += f.UncompressedSize64
+			if maxSize > 0 && size > uint64(maxSize) {
+				return nil, errArchiveTooLarge
+			}
+		}
+		return FromIOFS(zr), nil
+	case bytes.HasPrefix(src, []byte{0x1f, 0x8b}):
+		return newTarGzFS(bytes.NewReader(src), maxSize)
+	default:
+		return nil, errors.New("not a zip or tar.gz archive")
+	}
+}
+
+// splitArchivePath splits the given path into the path of an archive and
+// any "//subdir" selector following it, as findSubModuleSourcePath does for
+// module sources.
+func splitArchivePath(path string) (archive, subdir string) {
+	subdir = findSubModuleSourcePath(path)
+	if subdir == "" {
+		return path, ""
+	}
+	archive = strings.TrimSuffix(path, "//"+subdir)
+	if archiveExt(archive) == "" {
+		// The double slash isn't a selector following an archive.
+		return path, ""
+	}
+	return archive, subdir
+}
+
+func archiveExt(path string) string {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(strings.ToLower(path), ext) {
+			return ext
+		}
+	}
+	return ""
+}
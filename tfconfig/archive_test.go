@@ -0,0 +1,168 @@
+package tfconfig
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-test/deep"
+)
+
+func TestIsArchivePath(t *testing.T) {
+	tests := map[string]bool{
+		"module.zip":                 true,
+		"dist/module.tar.gz":         true,
+		"dist/module.TGZ":            true,
+		"module.zip//modules/vpc":    true,
+		"module.tar.gz//modules/vpc": true,
+		"module":                     false,
+		"module.tar":                 false,
+		"dist//module":               false,
+	}
+	for path, want := range tests {
+		if got := IsArchivePath(path); got != want {
+			t.Errorf("IsArchivePath(%q) = %t; want %t", path, got, want)
+		}
+	}
+}
+
+func TestLoadModuleFromArchive(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		t.Run(ext, func(t *testing.T) {
+			archive := writeTestArchive(t, filepath.Join("testdata", "module-tree"), ext)
+
+			module, diags := LoadModuleFromArchive(archive)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			if diff := deep.Equal(SortedKeysOfMap(module.ModuleCalls), []string{"missing", "network", "storage"}); diff != nil {
+				t.Errorf("wrong module calls: %s", diff)
+			}
+			if got, want := module.Variables["name"].Pos.Filename, "main.tf"; got != want {
+				t.Errorf("wrong filename %q; want %q", got, want)
+			}
+
+			module, diags = LoadModuleFromArchive(archive + "//network/subnet")
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			if got, want := module.Variables["vpc"].Pos.Filename, filepath.Join("network", "subnet", "main.tf"); got != want {
+				t.Errorf("wrong filename %q; want %q", got, want)
+			}
+
+			// The modules installed in the archive's .terraform/modules
+			// directory are found too.
+			fsys, dir, err := OpenArchive(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tree, _ := LoadModuleTreeFromFilesystem(fsys, dir)
+			var got []string
+			tree.Walk(func(t *ModuleTree) error {
+				got = append(got, t.Address)
+				return nil
+			})
+			want := []string{"", "module.network", "module.network.module.subnet", "module.storage"}
+			if diff := deep.Equal(got, want); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadModuleFromArchiveMissing(t *testing.T) {
+	_, diags := LoadModuleFromArchive(filepath.Join("testdata", "missing.zip"))
+	if len(diags) != 1 || diags[0].Code != DiagCodeReadArchive {
+		t.Fatalf("wrong diagnostics %#v; want one with code %q", diags, DiagCodeReadArchive)
+	}
+}
+
+func TestNewTarGzFS(t *testing.T) {
+	archive := writeTestArchive(t, filepath.Join("testdata", "module-tree"), ".tar.gz")
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fsys, err := NewTarGzFS(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(ToIOFS(fsys), "main.tf", "network/subnet/main.tf", ".terraform/modules/storage/main.tf"); err != nil {
+		t.Error(err)
+	}
+}
+
+// writeTestArchive writes the files in the given directory to a new archive
+// in a temporary directory, returning its path.
+func writeTestArchive(t *testing.T, dir, ext string) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "module"+ext)
+	out, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	var add func(name string, src []byte) error
+	var finish func() error
+	switch ext {
+	case ".zip":
+		zw := zip.NewWriter(out)
+		add = func(name string, src []byte) error {
+			w, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(src)
+			return err
+		}
+		finish = zw.Close
+	default:
+		gw := gzip.NewWriter(out)
+		tw := tar.NewWriter(gw)
+		add = func(name string, src []byte) error {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(src))}); err != nil {
+				return err
+			}
+			_, err := tw.Write(src)
+			return err
+		}
+		finish = func() error {
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			return gw.Close()
+		}
+	}
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return add(filepath.ToSlash(rel), src)
+	})
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
@@ -0,0 +1,305 @@
+package tfconfig
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Cache reuses the modules loaded before from the same files, and the
+// modules enriched before with the same provider metadata, so that
+// inspecting the same module versions again is quicker. Modules are keyed
+// by hashes of the names and content of the files they were loaded from,
+// rather than by when those files were modified, so a Cache never returns
+// out of date results.
+//
+// Cached modules are stored as JSON, so each is a new copy that the caller
+// may change, equal in its JSON form to the module that was loaded. A Cache
+// is safe for concurrent use.
+type Cache struct {
+	store  CacheStore
+	hits   int64
+	misses int64
+}
+
+// CacheStore stores the entries of a Cache. Keys are hexadecimal strings.
+// Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the value stored with the given key, if any.
+	Get(key string) ([]byte, bool)
+
+	// Put stores the given value with the given key, replacing any value
+	// already stored. A store may discard values at any time, so failures
+	// to store them are not reported.
+	Put(key string, value []byte)
+}
+
+// CacheStats counts the lookups made in a Cache.
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// NewCache returns a Cache that keeps its entries in the given store, such
+// as one returned by NewMemoryCacheStore or NewDiskCacheStore.
+func NewCache(store CacheStore) *Cache {
+	return &Cache{store: store}
+}
+
+// Stats returns the number of lookups so far that found a cached module,
+// and the number that did not.
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadInt64(&c.hits),
+		Misses: atomic.LoadInt64(&c.misses),
+	}
+}
+
+// LoadModule is a variant of LoadModuleFromFilesystem that uses the cache.
+// To use the cache when loading many modules concurrently, set it in the
+// LoadOptions given to LoadModuleContext, LoadModuleTreeContext or
+// ScanContext.
+func (c *Cache) LoadModule(fs FS, dir string) (*Module, Diagnostics) {
+	return newLoader(context.Background(), fs, &LoadOptions{Parallelism: 1, Cache: c}).loadModule(dir)
+}
+
+// LoadIBMModule is a variant of LoadIBMModuleFromFilesystem that uses the
+// cache, both for the whole enriched module and for each module it calls.
+//
+// Enriched modules are keyed by their directory, a hash of the given
+// metadata and the given installed modules, and are only reused if the
+// files of each of the modules it called are unchanged.
+func (c *Cache) LoadIBMModule(fs FS, dir string, metadata ProviderMetadata, fileStruct map[string]interface{}) (*Module, Diagnostics) {
+	l := newLoader(context.Background(), fs, &LoadOptions{Parallelism: 1, Cache: c})
+	key, err := ibmModuleCacheKey(dir, metadata, fileStruct)
+	if err != nil {
+		// Metadata that can't be hashed isn't cached.
+		return loadIBMModule(l, dir, metadata, fileStruct, "")
+	}
+
+	var entry ibmModuleCacheEntry
+	if c.get(key, &entry) && entry.Module != nil && validCacheDeps(l, entry.Deps) {
+		atomic.AddInt64(&c.hits, 1)
+		return entry.Module, entry.Module.Diagnostics
+	}
+	atomic.AddInt64(&c.misses, 1)
+
+	l.deps = make(map[string]string)
+	module, diags := loadIBMModule(l, dir, metadata, fileStruct, "")
+	for _, depKey := range l.deps {
+		if depKey == "" {
+			// A module that couldn't be read might be readable next time.
+			return module, diags
+		}
+	}
+	c.put(key, &ibmModuleCacheEntry{Module: module, Deps: l.deps})
+	return module, diags
+}
+
+// CheckForInitDirectoryAndLoadIBMModule is a variant of
+// CheckForInitDirectoryAndLoadIBMModuleFromFilesystem that uses the cache
+// as LoadIBMModule does.
+func (c *Cache) CheckForInitDirectoryAndLoadIBMModule(fs FS, dir string, metadata ProviderMetadata) (*Module, Diagnostics) {
+	return checkForInitDirectoryAndLoadIBMModule(fs, dir, metadata, c)
+}
+
+// ibmModuleCacheEntry is the value cached for an enriched module.
+type ibmModuleCacheEntry struct {
+	Module *Module `json:"module"`
+
+	// Deps are the cache keys of the files of each module directory that
+	// was loaded to enrich the module.
+	Deps map[string]string `json:"deps"`
+}
+
+func (c *Cache) getModule(key string) (*Module, bool) {
+	var module *Module
+	if c.get(key, &module) && module != nil {
+		atomic.AddInt64(&c.hits, 1)
+		return module, true
+	}
+	atomic.AddInt64(&c.misses, 1)
+	return nil, false
+}
+
+func (c *Cache) get(key string, v interface{}) bool {
+	src, ok := c.store.Get(key)
+	if !ok {
+		return false
+	}
+	// Entries that can't be decoded, such as those written by an older
+	// version, are ignored.
+	return json.Unmarshal(src, v) == nil
+}
+
+func (c *Cache) put(key string, v interface{}) {
+	src, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	c.store.Put(key, src)
+}
+
+// cacheKeyVersion is included in every cache key, so that it can be changed
+// when the cached values are no longer compatible.
+const cacheKeyVersion = "tfconfig-cache-1"
+
+// moduleCacheKey returns the cache key of the module in the given directory
+// with the given files, or an empty string if the files could not all be
+// read.
+func moduleCacheKey(dir string, files []moduleFile, readDiags hcl.Diagnostics) string {
+	if readDiags.HasErrors() {
+		return ""
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00module\x00%s\x00", cacheKeyVersion, dir)
+	for _, f := range files {
+		if f.src == nil {
+			return ""
+		}
+		fmt.Fprintf(h, "%s\x00%x\x00", f.name, sha256.Sum256(f.src))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ibmModuleCacheKey returns the cache key of the module in the given
+// directory enriched with the given metadata and installed modules.
+func ibmModuleCacheKey(dir string, metadata ProviderMetadata, fileStruct map[string]interface{}) (string, error) {
+	// Maps are encoded with sorted keys, so equal metadata has equal JSON.
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00ibm\x00%s\x00%x\x00", cacheKeyVersion, dir, sha256.Sum256(metadataJSON))
+	for _, name := range SortedKeysOfMap(fileStruct) {
+		fmt.Fprintf(h, "%s\x00", name)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// validCacheDeps reports whether the files of each of the given module
+// directories still have the given cache keys.
+func validCacheDeps(l *loader, deps map[string]string) bool {
+	for dir, key := range deps {
+		files, readDiags := readModuleFiles(l, dir)
+		if moduleCacheKey(dir, files, readDiags) != key {
+			return false
+		}
+	}
+	return true
+}
+
+// NewMemoryCacheStore returns a CacheStore that keeps up to the given
+// number of entries in memory, discarding those least recently used.
+func NewMemoryCacheStore(maxEntries int) CacheStore {
+	return &memoryCacheStore{
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+type memoryCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	lru        *list.List // of *memoryCacheEntry, most recently used first
+	entries    map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key   string
+	value []byte
+}
+
+func (s *memoryCacheStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(elem)
+	return elem.Value.(*memoryCacheEntry).value, true
+}
+
+func (s *memoryCacheStore) Put(key string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		elem.Value.(*memoryCacheEntry).value = value
+		s.lru.MoveToFront(elem)
+		return
+	}
+	s.entries[key] = s.lru.PushFront(&memoryCacheEntry{key: key, value: value})
+	for s.lru.Len() > s.maxEntries {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// NewDiskCacheStore returns a CacheStore that keeps its entries as files in
+// the given directory, creating it if necessary, so that they can be reused
+// by later processes. Entries are never discarded, so the directory can be
+// removed to clear the cache.
+func NewDiskCacheStore(dir string) (CacheStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &diskCacheStore{dir: dir}, nil
+}
+
+type diskCacheStore struct {
+	dir string
+}
+
+func (s *diskCacheStore) Get(key string) ([]byte, bool) {
+	src, err := ioutil.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return src, true
+}
+
+func (s *diskCacheStore) Put(key string, value []byte) {
+	filename := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return
+	}
+	// Entries are written to a temporary file and then renamed, so that
+	// concurrent readers never see part of one.
+	f, err := ioutil.TempFile(filepath.Dir(filename), key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(value)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+// path returns the path of the file for the given key, in a subdirectory
+// named after its first two characters to keep directories small.
+func (s *diskCacheStore) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(s.dir, key)
+	}
+	return filepath.Join(s.dir, key[:2], key)
+}
@@ -0,0 +1,153 @@
+package tfconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheLoadModule(t *testing.T) {
+	fixtures, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := NewCache(NewMemoryCacheStore(1000))
+	var count int64
+	for _, info := range fixtures {
+		if !info.IsDir() {
+			continue
+		}
+		count++
+		dir := filepath.Join("testdata", info.Name())
+		t.Run(info.Name(), func(t *testing.T) {
+			want, wantDiags := LoadModule(dir)
+			for i := 0; i < 2; i++ {
+				got, gotDiags := cache.LoadModule(NewOsFs(), dir)
+				assertSameJSON(t, got, want)
+				assertSameJSON(t, gotDiags, wantDiags)
+			}
+		})
+	}
+	if got, want := cache.Stats(), (CacheStats{Hits: count, Misses: count}); got != want {
+		t.Errorf("wrong stats %#v; want %#v", got, want)
+	}
+}
+
+func TestCacheChangedFiles(t *testing.T) {
+	base := FromIOFS(testScanFS)
+	dir := filepath.Join("repo", "modules", "vpc")
+	cache := NewCache(NewMemoryCacheStore(10))
+
+	cache.LoadModule(base, dir)
+	module, _ := cache.LoadModule(base, dir)
+	if _, ok := module.Variables["name"]; !ok {
+		t.Errorf("cached module has no variable")
+	}
+
+	edited := NewOverlayFS(base, map[string][]byte{
+		filepath.Join(dir, "main.tf"): []byte("variable \"edited\" {}\n"),
+	}, nil)
+	module, _ = cache.LoadModule(edited, dir)
+	if _, ok := module.Variables["edited"]; !ok {
+		t.Errorf("out of date module was returned for changed files")
+	}
+
+	added := NewOverlayFS(base, map[string][]byte{
+		filepath.Join(dir, "extra.tf"): []byte("variable \"extra\" {}\n"),
+	}, nil)
+	module, _ = cache.LoadModule(added, dir)
+	if _, ok := module.Variables["extra"]; !ok {
+		t.Errorf("out of date module was returned for an added file")
+	}
+
+	if got, want := cache.Stats(), (CacheStats{Hits: 1, Misses: 3}); got != want {
+		t.Errorf("wrong stats %#v; want %#v", got, want)
+	}
+
+	// Modules from the cache can be changed without changing the cache.
+	module.Variables["extra"].Description = "changed"
+	module, _ = cache.LoadModule(added, dir)
+	if got := module.Variables["extra"].Description; got != "" {
+		t.Errorf("cached module was changed: %q", got)
+	}
+}
+
+func TestCacheLoadIBMModule(t *testing.T) {
+	dir := filepath.Join("testdata", "ibm-metadata")
+	metadata, diags := ParseProviderMetadata([]byte(`{"Resources": {"ibm_is_subnet": [{"name": "zone", "description": "Zone of the subnet"}]}}`))
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	want, wantDiags := CheckForInitDirectoryAndLoadIBMModuleFromFilesystem(NewOsFs(), dir, metadata)
+
+	cache := NewCache(NewMemoryCacheStore(10))
+	for i := 0; i < 2; i++ {
+		got, gotDiags := cache.CheckForInitDirectoryAndLoadIBMModule(NewOsFs(), dir, metadata)
+		assertSameJSON(t, got, want)
+		assertSameJSON(t, gotDiags, wantDiags)
+	}
+	// The first load misses the enriched module and the two modules it
+	// loads, and the second hits the enriched module.
+	if got, want := cache.Stats(), (CacheStats{Hits: 1, Misses: 3}); got != want {
+		t.Errorf("wrong stats %#v; want %#v", got, want)
+	}
+
+	// Changing a called module invalidates the enriched module, but not
+	// the root module.
+	child := filepath.Join(dir, ".terraform", "modules", "subnet", "modules", "basic", "main.tf")
+	src, err := ioutil.ReadFile(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := NewOverlayFS(NewOsFs(), map[string][]byte{
+		child: append(src, []byte("\nvariable \"added\" {}\n")...),
+	}, nil)
+	cache.CheckForInitDirectoryAndLoadIBMModule(edited, dir, metadata)
+	if got, want := cache.Stats(), (CacheStats{Hits: 2, Misses: 5}); got != want {
+		t.Errorf("wrong stats %#v; want %#v", got, want)
+	}
+
+	// Different metadata is cached separately.
+	cache.CheckForInitDirectoryAndLoadIBMModule(NewOsFs(), dir, nil)
+	if got, want := cache.Stats(), (CacheStats{Hits: 4, Misses: 6}); got != want {
+		t.Errorf("wrong stats %#v; want %#v", got, want)
+	}
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	store := NewMemoryCacheStore(2)
+	store.Put("a", []byte("1"))
+	store.Put("b", []byte("2"))
+	store.Get("a")
+	store.Put("c", []byte("3"))
+	if _, ok := store.Get("b"); ok {
+		t.Errorf("least recently used entry was kept")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := store.Get(key); !ok {
+			t.Errorf("entry %q was discarded", key)
+		}
+	}
+}
+
+func TestDiskCacheStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	store, err := NewDiskCacheStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := filepath.Join("testdata", "module-tree")
+	want, _ := LoadModuleTree(fixture)
+
+	opts := &LoadOptions{Cache: NewCache(store)}
+	LoadModuleTreeContext(context.Background(), NewOsFs(), fixture, opts)
+
+	// A new cache, as in a later process, reuses the stored modules.
+	opts.Cache = NewCache(store)
+	got, _ := LoadModuleTreeContext(context.Background(), NewOsFs(), fixture, opts)
+	assertSameJSON(t, got, want)
+	if stats := opts.Cache.Stats(); stats.Misses != 0 || stats.Hits != 4 {
+		t.Errorf("wrong stats %#v", stats)
+	}
+}
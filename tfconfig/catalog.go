@@ -0,0 +1,458 @@
+package tfconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// CatalogManifest is a catalog onboarding manifest in the style of
+// ibm_catalog.json, describing a module as a product with a single flavor.
+type CatalogManifest struct {
+	Products []*CatalogProduct `json:"products"`
+}
+
+// CatalogProduct is a single product entry in a CatalogManifest.
+type CatalogProduct struct {
+	Name        string           `json:"name"`
+	Label       string           `json:"label,omitempty"`
+	ProductKind string           `json:"product_kind,omitempty"`
+	Flavors     []*CatalogFlavor `json:"flavors"`
+}
+
+// CatalogFlavor is a deployable variant of a CatalogProduct. Its
+// configuration and outputs are derived from a module's variables and
+// outputs.
+type CatalogFlavor struct {
+	Name             string           `json:"name"`
+	Label            string           `json:"label,omitempty"`
+	WorkingDirectory string           `json:"working_directory,omitempty"`
+	Configuration    []*CatalogInput  `json:"configuration"`
+	Outputs          []*CatalogOutput `json:"outputs"`
+}
+
+// CatalogInput describes a single input variable in a CatalogFlavor.
+type CatalogInput struct {
+	Key           string           `json:"key"`
+	Type          string           `json:"type,omitempty"`
+	DefaultValue  interface{}      `json:"default_value,omitempty"`
+	Description   string           `json:"description,omitempty"`
+	Required      bool             `json:"required"`
+	Hidden        bool             `json:"hidden,omitempty"`
+	Immutable     bool             `json:"immutable,omitempty"`
+	CloudDataType string           `json:"cloud_data_type,omitempty"`
+	Options       []*CatalogOption `json:"options,omitempty"`
+}
+
+// CatalogOption is one of the allowed values of a CatalogInput.
+type CatalogOption struct {
+	DisplayName string `json:"displayname"`
+	Value       string `json:"value"`
+}
+
+// CatalogOutput describes a single output value in a CatalogFlavor.
+type CatalogOutput struct {
+	Key         string `json:"key"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// CatalogDrift describes a difference between an existing catalog manifest
+// and the module it describes, as found by MergeCatalogManifest.
+type CatalogDrift struct {
+	// Key is the input or output the difference relates to, such as
+	// "configuration.region" or "outputs.vpc_id".
+	Key string `json:"key"`
+
+	// Field is the name of the differing field, or empty if the whole
+	// input or output was added or removed.
+	Field string `json:"field,omitempty"`
+
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+
+	// Kept is true if the existing value was retained because it may have
+	// been edited by hand, rather than being replaced by the new value.
+	Kept bool `json:"kept,omitempty"`
+}
+
+func (d CatalogDrift) String() string {
+	switch {
+	case d.Field == "" && d.Old == nil:
+		return fmt.Sprintf("%s: added", d.Key)
+	case d.Field == "" && d.New == nil:
+		return fmt.Sprintf("%s: removed", d.Key)
+	case d.Kept:
+		return fmt.Sprintf("%s.%s: kept %s, module has %s", d.Key, d.Field, catalogJSONString(d.Old), catalogJSONString(d.New))
+	default:
+		return fmt.Sprintf("%s.%s: changed from %s to %s", d.Key, d.Field, catalogJSONString(d.Old), catalogJSONString(d.New))
+	}
+}
+
+// catalogAuthoritativeFields are the fields of generated inputs and outputs
+// that are determined entirely by the module source, and so are always
+// replaced when merging into an existing manifest. All other generated
+// fields are only filled in when missing, since catalog authors commonly
+// refine them by hand.
+//
+// The type is not authoritative, as authors may choose a more specific type
+// than the one generated, such as "password" for a secret string.
+var catalogAuthoritativeFields = map[string]bool{
+	"key":           true,
+	"default_value": true,
+	"required":      true,
+}
+
+// NewCatalogManifest returns a catalog manifest describing the given module,
+// which typically has been enriched by LoadIBMModule.
+func NewCatalogManifest(module *Module) *CatalogManifest {
+	name := filepath.Base(filepath.Clean(module.Path))
+	flavor := &CatalogFlavor{
+		Name:             "standard",
+		Label:            "Standard",
+		WorkingDirectory: "./",
+		Configuration:    []*CatalogInput{},
+		Outputs:          []*CatalogOutput{},
+	}
+	for _, k := range SortedKeysOfMap(module.Variables) {
+		flavor.Configuration = append(flavor.Configuration, catalogInput(module.Variables[k]))
+	}
+	for _, k := range SortedKeysOfMap(module.Outputs) {
+		o := module.Outputs[k]
+		flavor.Outputs = append(flavor.Outputs, &CatalogOutput{
+			Key:         k,
+			Type:        o.Type,
+			Description: o.Description,
+		})
+	}
+	return &CatalogManifest{
+		Products: []*CatalogProduct{
+			{
+				Name:        name,
+				Label:       name,
+				ProductKind: "module",
+				Flavors:     []*CatalogFlavor{flavor},
+			},
+		},
+	}
+}
+
+func catalogInput(v *Variable) *CatalogInput {
+	in := &CatalogInput{
+		Key:           v.Name,
+		Type:          v.Type,
+		DefaultValue:  v.Default,
+		Description:   v.Description,
+		Required:      v.Required != nil && *v.Required,
+		Hidden:        v.Hidden != nil && *v.Hidden,
+		Immutable:     v.Immutable != nil && *v.Immutable,
+		CloudDataType: v.CloudDataType,
+	}
+	if v.Sensitive != nil && *v.Sensitive && (v.Type == "" || v.Type == "string") {
+		// The catalog represents secrets as a distinct type, so that
+		// their values are masked.
+		in.Type = "password"
+	}
+	if v.AllowedValues != "" {
+		for _, opt := range strings.Split(v.AllowedValues, ",") {
+			opt = strings.TrimSpace(opt)
+			in.Options = append(in.Options, &CatalogOption{DisplayName: opt, Value: opt})
+		}
+	}
+	return in
+}
+
+// RenderCatalogManifest writes a new catalog manifest describing the given
+// module.
+func RenderCatalogManifest(w io.Writer, module *Module) error {
+	src, err := marshalCatalogJSON(NewCatalogManifest(module))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// MergeCatalogManifest updates the given existing catalog manifest source to
+// describe the given module, returning the updated source along with a
+// description of each difference that was found.
+//
+// The flavor to update is selected by name, or the first flavor of the first
+// product is used if flavorName is empty. The inputs and outputs of that
+// flavor are matched to the module's variables and outputs by key: the
+// default and required flags are updated from the module, other fields,
+// including the type, are only filled in where missing, and any fields not produced by
+// NewCatalogManifest are left untouched. Inputs and outputs the module no
+// longer declares are removed, and new ones are appended. The order of the
+// existing document is preserved.
+func MergeCatalogManifest(existing []byte, module *Module, flavorName string) ([]byte, []CatalogDrift, error) {
+	doc, err := decodeCatalogJSON(existing)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid catalog manifest: %s", err)
+	}
+	root, ok := doc.(*catalogObject)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid catalog manifest: root must be an object")
+	}
+	flavor := findCatalogFlavor(root, flavorName)
+	if flavor == nil {
+		if flavorName != "" {
+			return nil, nil, fmt.Errorf("catalog manifest has no flavor named %q", flavorName)
+		}
+		return nil, nil, fmt.Errorf("catalog manifest has no flavors")
+	}
+
+	generated := NewCatalogManifest(module).Products[0].Flavors[0]
+	var drift []CatalogDrift
+	sections := []struct {
+		name    string
+		entries interface{}
+	}{
+		{"configuration", generated.Configuration},
+		{"outputs", generated.Outputs},
+	}
+	for _, section := range sections {
+		src, err := json.Marshal(section.entries)
+		if err != nil {
+			return nil, nil, err
+		}
+		want, err := decodeCatalogJSON(src)
+		if err != nil {
+			return nil, nil, err
+		}
+		have, _ := flavor.get(section.name).([]interface{})
+		merged, sectionDrift := mergeCatalogEntries(section.name, have, want.([]interface{}))
+		flavor.set(section.name, merged)
+		drift = append(drift, sectionDrift...)
+	}
+
+	src, err := marshalCatalogJSON(root)
+	return src, drift, err
+}
+
+func findCatalogFlavor(root *catalogObject, name string) *catalogObject {
+	products, _ := root.get("products").([]interface{})
+	for _, p := range products {
+		product, ok := p.(*catalogObject)
+		if !ok {
+			continue
+		}
+		flavors, _ := product.get("flavors").([]interface{})
+		for _, f := range flavors {
+			flavor, ok := f.(*catalogObject)
+			if !ok {
+				continue
+			}
+			if name == "" || flavor.get("name") == name {
+				return flavor
+			}
+		}
+	}
+	return nil
+}
+
+func mergeCatalogEntries(section string, have, want []interface{}) ([]interface{}, []CatalogDrift) {
+	var drift []CatalogDrift
+	wantByKey := make(map[string]*catalogObject, len(want))
+	for _, w := range want {
+		obj := w.(*catalogObject)
+		wantByKey[obj.get("key").(string)] = obj
+	}
+
+	merged := make([]interface{}, 0, len(want))
+	seen := make(map[string]bool, len(have))
+	for _, h := range have {
+		existing, ok := h.(*catalogObject)
+		if !ok {
+			merged = append(merged, h)
+			continue
+		}
+		key, _ := existing.get("key").(string)
+		w, ok := wantByKey[key]
+		if !ok {
+			drift = append(drift, CatalogDrift{Key: section + "." + key, Old: key})
+			continue
+		}
+		seen[key] = true
+		for _, field := range w.keys {
+			newVal := w.get(field)
+			oldVal, exists := existing.values[field]
+			if exists && catalogValuesEqual(oldVal, newVal) {
+				continue
+			}
+			d := CatalogDrift{Key: section + "." + key, Field: field, Old: oldVal, New: newVal}
+			if exists && !catalogAuthoritativeFields[field] {
+				d.Kept = true
+			} else {
+				existing.set(field, newVal)
+			}
+			drift = append(drift, d)
+		}
+		// Generated fields that are now omitted, such as a default that
+		// was removed, are authoritative for the same reason.
+		for _, field := range SortedKeysOfMap(catalogAuthoritativeFields) {
+			if _, stillWanted := w.values[field]; stillWanted {
+				continue
+			}
+			if oldVal, exists := existing.values[field]; exists {
+				existing.remove(field)
+				drift = append(drift, CatalogDrift{Key: section + "." + key, Field: field, Old: oldVal})
+			}
+		}
+		merged = append(merged, existing)
+	}
+	for _, w := range want {
+		obj := w.(*catalogObject)
+		key := obj.get("key").(string)
+		if !seen[key] {
+			merged = append(merged, obj)
+			drift = append(drift, CatalogDrift{Key: section + "." + key, New: key})
+		}
+	}
+	return merged, drift
+}
+
+// catalogObject is a JSON object that remembers the order of its
+// properties, so that existing manifests can be updated without reordering
+// them.
+type catalogObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *catalogObject) get(key string) interface{} {
+	return o.values[key]
+}
+
+func (o *catalogObject) set(key string, val interface{}) {
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = val
+}
+
+func (o *catalogObject) remove(key string) {
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// MarshalJSON implements encoding/json.Marshaler.
+func (o *catalogObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalCatalogValue(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		val, err := marshalCatalogValue(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func decodeCatalogJSON(src []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+	return decodeCatalogJSONValue(dec)
+}
+
+func decodeCatalogJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &catalogObject{values: make(map[string]interface{})}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeCatalogJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.set(keyTok.(string), val)
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			val, err := decodeCatalogJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err = dec.Token()
+		return arr, err
+	default:
+		return tok, nil
+	}
+}
+
+// marshalCatalogValue is like json.Marshal but doesn't escape HTML
+// characters, which would otherwise cause noisy changes to descriptions in
+// existing manifests.
+func marshalCatalogValue(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+func marshalCatalogJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// catalogValuesEqual compares two decoded JSON values by their meaning,
+// ignoring property order and number formatting.
+func catalogValuesEqual(a, b interface{}) bool {
+	var plainA, plainB interface{}
+	if err := json.Unmarshal([]byte(catalogJSONString(a)), &plainA); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(catalogJSONString(b)), &plainB); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(plainA, plainB)
+}
+
+func catalogJSONString(v interface{}) string {
+	src, err := marshalCatalogValue(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(src)
+}
@@ -0,0 +1,122 @@
+package tfconfig
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestMergeCatalogManifest(t *testing.T) {
+	required := true
+	module := NewModule("example")
+	module.Variables["region"] = &Variable{
+		Name:          "region",
+		Type:          "string",
+		Description:   "Region to deploy into",
+		Required:      &required,
+		CloudDataType: "region",
+	}
+	module.Variables["prefix"] = &Variable{
+		Name:    "prefix",
+		Type:    "string",
+		Default: "demo",
+	}
+	module.Outputs["vpc_id"] = &Output{
+		Name:        "vpc_id",
+		Description: "ID of the VPC",
+	}
+
+	existing := `{
+  "products": [
+    {
+      "name": "example",
+      "flavors": [
+        {
+          "name": "standard",
+          "install_type": "fullstack",
+          "configuration": [
+            {
+              "key": "region",
+              "type": "password",
+              "display_name": "Region",
+              "description": "The region, as curated by a <human>",
+              "required": false
+            },
+            {
+              "key": "zone",
+              "type": "string"
+            }
+          ],
+          "outputs": []
+        }
+      ]
+    }
+  ]
+}
+`
+	got, drift, err := MergeCatalogManifest([]byte(existing), module, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "products": [
+    {
+      "name": "example",
+      "flavors": [
+        {
+          "name": "standard",
+          "install_type": "fullstack",
+          "configuration": [
+            {
+              "key": "region",
+              "type": "password",
+              "display_name": "Region",
+              "description": "The region, as curated by a <human>",
+              "required": true,
+              "cloud_data_type": "region"
+            },
+            {
+              "key": "prefix",
+              "type": "string",
+              "default_value": "demo",
+              "required": false
+            }
+          ],
+          "outputs": [
+            {
+              "key": "vpc_id",
+              "description": "ID of the VPC"
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+	if diff := deep.Equal(string(got), want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+
+	var gotDrift []string
+	for _, d := range drift {
+		gotDrift = append(gotDrift, d.String())
+	}
+	wantDrift := []string{
+		`configuration.region.type: kept "password", module has "string"`,
+		`configuration.region.description: kept "The region, as curated by a <human>", module has "Region to deploy into"`,
+		`configuration.region.required: changed from false to true`,
+		`configuration.region.cloud_data_type: changed from null to "region"`,
+		`configuration.zone: removed`,
+		`configuration.prefix: added`,
+		`outputs.vpc_id: added`,
+	}
+	if diff := deep.Equal(gotDrift, wantDrift); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
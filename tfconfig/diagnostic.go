@@ -14,11 +14,114 @@ type Diagnostic struct {
 	Summary  string       `json:"summary"`
 	Detail   string       `json:"detail,omitempty"`
 
+	// Code identifies the kind of problem described, and is stable across
+	// releases so that callers can match on it rather than on Summary. It is
+	// one of the DiagCode constants, or the ID of the rule that produced the
+	// diagnostic.
+	Code string `json:"code,omitempty"`
+
 	// Pos is not populated for all diagnostics, but when populated should
 	// indicate a particular line that the described problem relates to.
 	Pos *SourcePos `json:"pos,omitempty"`
+
+	// Range is populated, along with Pos, for diagnostics whose problem
+	// relates to a particular range of characters known to the HCL parser.
+	Range *SourceRange `json:"range,omitempty"`
+
+	// Snippet is the source code that the diagnostic relates to, populated
+	// only by Diagnostics.WithSnippets.
+	Snippet *DiagnosticSnippet `json:"snippet,omitempty"`
+
+	// Rule is the ID of the rule that produced the diagnostic, for those
+	// returned by Lint and LintTree.
+	Rule string `json:"rule,omitempty"`
+
+	// Module is the address of the module that the diagnostic came from,
+	// such as "module.vpc", for those about modules called by the module
+	// loaded by LoadIBMModule. It is empty for the module itself.
+	Module string `json:"module,omitempty"`
 }
 
+// Diagnostic codes for problems found while loading configuration. The
+// diagnostics returned by rules have the ID of the rule as their code.
+const (
+	// DiagCodeHCL is the code of problems reported by the HCL parser
+	// itself, such as syntax errors and values of the wrong type.
+	DiagCodeHCL = "hcl"
+
+	// DiagCodeInvalidConfig is the code of problems found in configuration
+	// written in the legacy (Terraform 0.11 and earlier) language.
+	DiagCodeInvalidConfig = "invalid_config"
+
+	// DiagCodeReadDir and DiagCodeReadFile are the codes of failures to
+	// read a module directory or one of its files.
+	DiagCodeReadDir  = "read_dir"
+	DiagCodeReadFile = "read_file"
+
+	// DiagCodeReadArchive is the code of failures to read a module archive
+	// with LoadModuleFromArchive.
+	DiagCodeReadArchive = "read_archive"
+
+	// DiagCodeReadRevision is the code of failures to read a module from a
+	// git revision with LoadModuleAtRevision.
+	DiagCodeReadRevision = "read_revision"
+
+	// DiagCodeScanPattern is the code of invalid glob patterns given to
+	// Scan.
+	DiagCodeScanPattern = "scan_pattern"
+
+	// DiagCodeCanceled is the code of loads that stopped before they were
+	// complete because their context was canceled or its deadline passed.
+	DiagCodeCanceled = "canceled"
+
+	// DiagCodeProviderSource is the code of conflicting provider source
+	// addresses in required_providers.
+	DiagCodeProviderSource = "provider_source"
+
+	// DiagCodeRequiredProviders is the code of invalid required_providers
+	// entries.
+	DiagCodeRequiredProviders = "required_providers"
+
+	// DiagCodeProviderReference is the code of invalid references to
+	// provider configurations, such as in the provider argument of a
+	// resource.
+	DiagCodeProviderReference = "provider_reference"
+
+	// DiagCodeModuleNotInstalled is the code of module calls whose source
+	// could not be found locally, and DiagCodeModuleTreeDepth the code of
+	// module trees that are nested too deeply.
+	DiagCodeModuleNotInstalled = "module_not_installed"
+	DiagCodeModuleTreeDepth    = "module_tree_depth"
+
+	// DiagCodeModuleSource is the code of module calls whose source is not
+	// supported by LoadIBMModule.
+	DiagCodeModuleSource = "module_source"
+
+	// DiagCodeNotInitialized is the code of modules that have not been
+	// initialized with "terraform init" before being loaded with
+	// CheckForInitDirectoryAndLoadIBMModule.
+	DiagCodeNotInitialized = "not_initialized"
+
+	// DiagCodeMetadata is the code of failures to read or parse a provider
+	// metadata file.
+	DiagCodeMetadata = "metadata"
+
+	// DiagCodeInvalidRuleFile is the code of problems found in the rule
+	// files read by LoadRuleFile and ParseRules.
+	DiagCodeInvalidRuleFile = "invalid_rule_file"
+
+	// DiagCodeUndeclaredVariable, DiagCodeInvalidVariableValue and
+	// DiagCodeMissingVariable are the codes of the problems found by
+	// ValidateTfvars.
+	DiagCodeUndeclaredVariable   = "undeclared_variable"
+	DiagCodeInvalidVariableValue = "invalid_variable_value"
+	DiagCodeMissingVariable      = "missing_variable"
+)
+
+// diagCodeExtra is used as the Extra value of HCL diagnostics created by this
+// package, to carry their code through diagnosticsHCL.
+type diagCodeExtra string
+
 // Diagnostics represents a sequence of diagnostics. This is the type that
 // should be returned from a function that might generate diagnostics.
 type Diagnostics []Diagnostic
@@ -85,6 +188,19 @@ func (s DiagSeverity) MarshalJSON() ([]byte, error) {
 	}
 }
 
+// UnmarshalJSON is an implementation of encoding/json.Unmarshaler
+func (s *DiagSeverity) UnmarshalJSON(src []byte) error {
+	switch string(src) {
+	case `"error"`:
+		*s = DiagError
+	case `"warning"`:
+		*s = DiagWarning
+	default:
+		return fmt.Errorf("invalid diagnostic severity %s", src)
+	}
+	return nil
+}
+
 func diagnosticsHCL(diags hcl.Diagnostics) Diagnostics {
 	if len(diags) == 0 {
 		return nil
@@ -94,6 +210,10 @@ func diagnosticsHCL(diags hcl.Diagnostics) Diagnostics {
 		ret[i] = Diagnostic{
 			Summary: diag.Summary,
 			Detail:  diag.Detail,
+			Code:    DiagCodeHCL,
+		}
+		if code, ok := diag.Extra.(diagCodeExtra); ok {
+			ret[i].Code = string(code)
 		}
 		switch diag.Severity {
 		case hcl.DiagError:
@@ -104,6 +224,7 @@ func diagnosticsHCL(diags hcl.Diagnostics) Diagnostics {
 		if diag.Subject != nil {
 			pos := sourcePosHCL(*diag.Subject)
 			ret[i].Pos = &pos
+			ret[i].Range = sourceRangeHCL(*diag.Subject)
 		}
 	}
 	return ret
@@ -120,6 +241,7 @@ func diagnosticsError(err error) Diagnostics {
 			Diagnostic{
 				Severity: DiagError,
 				Summary:  posErr.Err.Error(),
+				Code:     DiagCodeHCL,
 				Pos:      &pos,
 			},
 		}
@@ -129,6 +251,7 @@ func diagnosticsError(err error) Diagnostics {
 		Diagnostic{
 			Severity: DiagError,
 			Summary:  err.Error(),
+			Code:     DiagCodeInvalidConfig,
 		},
 	}
 }
@@ -0,0 +1,180 @@
+package tfconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// DiagnosticSnippet is an excerpt of the source code that a diagnostic
+// relates to, as in the JSON diagnostics of Terraform itself.
+type DiagnosticSnippet struct {
+	// Code is the text of the whole source lines that the diagnostic
+	// relates to, without the final newline.
+	Code string `json:"code"`
+
+	// StartLine is the line number in the source file of the first line
+	// of Code.
+	StartLine int `json:"start_line"`
+
+	// HighlightStartOffset and HighlightEndOffset are the byte offsets
+	// within Code of the start and end of the range that the diagnostic
+	// relates to, or of the non-blank part of its line if it only has a
+	// line number.
+	HighlightStartOffset int `json:"highlight_start_offset"`
+	HighlightEndOffset   int `json:"highlight_end_offset"`
+}
+
+// WithSnippets returns a copy of the receiver in which each diagnostic with
+// a source position in a file that can be read from the given FS has a
+// Snippet of the source code it relates to.
+func (diags Diagnostics) WithSnippets(fs FS) Diagnostics {
+	if diags == nil {
+		return nil
+	}
+	sources := make(map[string][]byte)
+	ret := make(Diagnostics, len(diags))
+	for i, diag := range diags {
+		ret[i] = diag
+		if diag.Snippet != nil || diag.Pos == nil || diag.Pos.Filename == "" {
+			continue
+		}
+		src, ok := sources[diag.Pos.Filename]
+		if !ok {
+			// Files that can't be read are remembered as nil, so that we
+			// only try once.
+			src, _ = fs.ReadFile(diag.Pos.Filename)
+			sources[diag.Pos.Filename] = src
+		}
+		if src != nil {
+			ret[i].Snippet = diagnosticSnippet(src, diag)
+		}
+	}
+	return ret
+}
+
+// diagnosticSnippet returns the snippet of the given source for the given
+// diagnostic, or nil if its position isn't within the source.
+func diagnosticSnippet(src []byte, diag Diagnostic) *DiagnosticSnippet {
+	if rng := diag.Range; rng != nil && rng.Start.Byte >= 0 && rng.Start.Byte <= rng.End.Byte && rng.End.Byte <= len(src) {
+		start := bytes.LastIndexByte(src[:rng.Start.Byte], '\n') + 1
+		end := rng.End.Byte
+		if end > rng.Start.Byte && src[end-1] == '\n' {
+			end--
+		}
+		if nl := bytes.IndexByte(src[end:], '\n'); nl >= 0 {
+			end += nl
+		} else {
+			end = len(src)
+		}
+		return &DiagnosticSnippet{
+			Code:                 string(src[start:end]),
+			StartLine:            rng.Start.Line,
+			HighlightStartOffset: rng.Start.Byte - start,
+			HighlightEndOffset:   rng.End.Byte - start,
+		}
+	}
+
+	lines := strings.Split(string(src), "\n")
+	if diag.Pos.Line < 1 || diag.Pos.Line > len(lines) {
+		return nil
+	}
+	line := strings.TrimSuffix(lines[diag.Pos.Line-1], "\r")
+	trimmed := strings.TrimLeft(line, " \t")
+	start := len(line) - len(trimmed)
+	return &DiagnosticSnippet{
+		Code:                 line,
+		StartLine:            diag.Pos.Line,
+		HighlightStartOffset: start,
+		HighlightEndOffset:   start + len(strings.TrimRight(trimmed, " \t")),
+	}
+}
+
+// RenderDiagnostics writes the given diagnostics for people to read, in the
+// style of Terraform itself: each has a heading with its severity, summary
+// and code, then its source position and the address of the module it came
+// from, then any source snippet with the
+// range it relates to underlined with carets, and finally its detail. Use
+// Diagnostics.WithSnippets to include source snippets.
+func RenderDiagnostics(w io.Writer, diags Diagnostics) error {
+	var buf bytes.Buffer
+	for _, diag := range diags {
+		severity := "Warning"
+		if diag.Severity == DiagError {
+			severity = "Error"
+		}
+		fmt.Fprintf(&buf, "%s: %s", severity, diag.Summary)
+		if diag.Code != "" {
+			fmt.Fprintf(&buf, " [%s]", diag.Code)
+		}
+		buf.WriteString("\n\n")
+
+		if diag.Pos != nil && diag.Pos.Filename != "" {
+			fmt.Fprintf(&buf, "  on %s line %d", diag.Pos.Filename, diag.Pos.Line)
+			if diag.Module != "" {
+				fmt.Fprintf(&buf, ", in %s", diag.Module)
+			}
+			buf.WriteString(":\n")
+			if diag.Snippet != nil {
+				renderDiagnosticSnippet(&buf, diag.Snippet)
+			}
+			buf.WriteString("\n")
+		} else if diag.Module != "" {
+			fmt.Fprintf(&buf, "  in %s\n\n", diag.Module)
+		}
+
+		if diag.Detail != "" {
+			fmt.Fprintf(&buf, "%s\n\n", diag.Detail)
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// renderDiagnosticSnippet writes each line of the given snippet with its
+// line number, followed by a line of carets under the part of it within the
+// snippet's highlighted range.
+func renderDiagnosticSnippet(buf *bytes.Buffer, snippet *DiagnosticSnippet) {
+	lines := strings.Split(snippet.Code, "\n")
+	width := len(fmt.Sprint(snippet.StartLine + len(lines) - 1))
+	gutter := strings.Repeat(" ", width+4)
+
+	offset := 0
+	for i, line := range lines {
+		lineStart, lineEnd := offset, offset+len(line)
+		offset = lineEnd + 1
+		line = strings.TrimSuffix(line, "\r")
+		fmt.Fprintf(buf, "  %*d: %s\n", width, snippet.StartLine+i, line)
+
+		start, end := snippet.HighlightStartOffset, snippet.HighlightEndOffset
+		if start < lineStart {
+			// Ranges spanning lines are underlined from the first non-blank
+			// character of the lines after the first.
+			start = lineStart + len(line) - len(strings.TrimLeft(line, " \t"))
+		}
+		if end > lineStart+len(line) {
+			end = lineStart + len(line)
+		}
+		if start > end || start > lineEnd || (start == end && start != snippet.HighlightStartOffset) {
+			continue
+		}
+
+		// Tabs are kept in the indentation of the carets so that they line
+		// up with the source line however wide the tabs are displayed.
+		var indent strings.Builder
+		for _, r := range line[:start-lineStart] {
+			if r == '\t' {
+				indent.WriteRune('\t')
+			} else {
+				indent.WriteRune(' ')
+			}
+		}
+		carets := utf8.RuneCountInString(line[start-lineStart : end-lineStart])
+		if carets == 0 {
+			carets = 1
+		}
+		fmt.Fprintf(buf, "%s%s%s\n", gutter, indent.String(), strings.Repeat("^", carets))
+	}
+}
@@ -0,0 +1,92 @@
+package tfconfig
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestRenderDiagnostics(t *testing.T) {
+	tests := map[string]bool{
+		"lint":                    true,
+		"provider-source-invalid": false,
+	}
+
+	for name, lint := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join("testdata", name)
+			module, diags := LoadModule(path)
+			if lint {
+				diags = append(diags, Lint(module, LintRules)...)
+			}
+
+			expected, err := ioutil.ReadFile(filepath.Join(path, name+".out.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := RenderDiagnostics(&buf, diags.WithSnippets(NewOsFs())); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(buf.String(), string(expected)); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderDiagnosticsRange(t *testing.T) {
+	src := []byte("locals {\n\tname = join(\"-\", [\n\t\t\"a\",\n\t])\n}\n")
+	diag := Diagnostic{
+		Severity: DiagError,
+		Summary:  "Invalid name",
+		Detail:   "The name is not valid.",
+		Code:     DiagCodeHCL,
+		Pos:      &SourcePos{Filename: "main.tf", Line: 2},
+		Range: &SourceRange{
+			Filename: "main.tf",
+			Start:    SourcePoint{Line: 2, Column: 9, Byte: 17},
+			End:      SourcePoint{Line: 4, Column: 4, Byte: 39},
+		},
+	}
+	diag.Snippet = diagnosticSnippet(src, diag)
+
+	want := &DiagnosticSnippet{
+		Code:                 "\tname = join(\"-\", [\n\t\t\"a\",\n\t])",
+		StartLine:            2,
+		HighlightStartOffset: 8,
+		HighlightEndOffset:   30,
+	}
+	if diff := deep.Equal(diag.Snippet, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDiagnostics(&buf, Diagnostics{diag}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Error: Invalid name [hcl]\n\n" +
+		"  on main.tf line 2:\n" +
+		"  2: \tname = join(\"-\", [\n" +
+		"     \t       ^^^^^^^^^^^\n" +
+		"  3: \t\t\"a\",\n" +
+		"     \t\t^^^^\n" +
+		"  4: \t])\n" +
+		"     \t^^\n" +
+		"\n" +
+		"The name is not valid.\n\n"
+	if diff := deep.Equal(buf.String(), expected); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
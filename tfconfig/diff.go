@@ -0,0 +1,380 @@
+package tfconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// SemverBump is the part of a module's semantic version that a change
+// requires to be incremented.
+type SemverBump string
+
+const (
+	BumpNone  SemverBump = "none"
+	BumpPatch SemverBump = "patch"
+	BumpMinor SemverBump = "minor"
+	BumpMajor SemverBump = "major"
+)
+
+func (b SemverBump) rank() int {
+	switch b {
+	case BumpPatch:
+		return 1
+	case BumpMinor:
+		return 2
+	case BumpMajor:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// ModuleChangeKind identifies a kind of change to the interface of a module.
+type ModuleChangeKind string
+
+const (
+	VariableAdded              ModuleChangeKind = "variable_added"
+	VariableRemoved            ModuleChangeKind = "variable_removed"
+	VariableTypeChanged        ModuleChangeKind = "variable_type_changed"
+	VariableDefaultChanged     ModuleChangeKind = "variable_default_changed"
+	VariableDefaultAdded       ModuleChangeKind = "variable_default_added"
+	VariableDefaultRemoved     ModuleChangeKind = "variable_default_removed"
+	VariableSensitiveAdded     ModuleChangeKind = "variable_sensitive_added"
+	VariableSensitiveRemoved   ModuleChangeKind = "variable_sensitive_removed"
+	VariableDescriptionChanged ModuleChangeKind = "variable_description_changed"
+
+	OutputAdded              ModuleChangeKind = "output_added"
+	OutputRemoved            ModuleChangeKind = "output_removed"
+	OutputValueChanged       ModuleChangeKind = "output_value_changed"
+	OutputTypeChanged        ModuleChangeKind = "output_type_changed"
+	OutputSensitiveAdded     ModuleChangeKind = "output_sensitive_added"
+	OutputSensitiveRemoved   ModuleChangeKind = "output_sensitive_removed"
+	OutputDescriptionChanged ModuleChangeKind = "output_description_changed"
+
+	ProviderAdded                  ModuleChangeKind = "provider_added"
+	ProviderRemoved                ModuleChangeKind = "provider_removed"
+	ProviderSourceChanged          ModuleChangeKind = "provider_source_changed"
+	ProviderConstraintsTightened   ModuleChangeKind = "provider_constraints_tightened"
+	ProviderConstraintsLoosened    ModuleChangeKind = "provider_constraints_loosened"
+	RequiredVersionRaised          ModuleChangeKind = "required_version_raised"
+	RequiredVersionLowered         ModuleChangeKind = "required_version_lowered"
+	ResourceAdded                  ModuleChangeKind = "resource_added"
+	ResourceRemoved                ModuleChangeKind = "resource_removed"
+	ResourceMoved                  ModuleChangeKind = "resource_moved"
+	ModuleCallAdded                ModuleChangeKind = "module_call_added"
+	ModuleCallRemoved              ModuleChangeKind = "module_call_removed"
+	ModuleCallMoved                ModuleChangeKind = "module_call_moved"
+	VersionConstraintsIncomparable ModuleChangeKind = "version_constraints_incomparable"
+)
+
+// ModuleChange is a single change to the interface of a module, as found
+// by Diff.
+type ModuleChange struct {
+	Kind ModuleChangeKind `json:"kind"`
+
+	// Address is the address of the changed object, such as "var.region",
+	// "output.vpc_id", "provider.ibm", "terraform" for the core version
+	// constraints, "ibm_is_vpc.vpc" or "module.network".
+	Address string `json:"address"`
+
+	Bump   SemverBump `json:"bump"`
+	Detail string     `json:"detail"`
+
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// ModuleDiff is the result of comparing two versions of a module.
+type ModuleDiff struct {
+	// Bump is the largest bump required by any of the changes, or BumpNone
+	// if there are no changes.
+	Bump    SemverBump      `json:"bump"`
+	Changes []*ModuleChange `json:"changes"`
+}
+
+// Breaking returns the changes that require a major version bump.
+func (d *ModuleDiff) Breaking() []*ModuleChange {
+	return d.changesWithBump(BumpMajor)
+}
+
+func (d *ModuleDiff) changesWithBump(bump SemverBump) []*ModuleChange {
+	var ret []*ModuleChange
+	for _, c := range d.Changes {
+		if c.Bump == bump {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Diff compares the interfaces of two versions of a module and classifies
+// each change by the semantic version bump that it requires.
+//
+// Changes that can break callers, such as removing a variable or output,
+// adding a variable without a default, changing a variable's type,
+// tightening provider or Terraform version constraints or removing a
+// managed resource or module call without a "moved" block, require a major
+// version bump. Backward-compatible additions and changes of behaviour,
+// such as a new optional variable or a changed default, require a minor
+// version bump, and documentation changes require a patch version bump.
+func Diff(old, new *Module) *ModuleDiff {
+	d := &ModuleDiff{Changes: []*ModuleChange{}}
+	add := func(kind ModuleChangeKind, addr string, bump SemverBump, oldVal, newVal interface{}, detail string, args ...interface{}) {
+		d.Changes = append(d.Changes, &ModuleChange{
+			Kind:    kind,
+			Address: addr,
+			Bump:    bump,
+			Detail:  fmt.Sprintf(detail, args...),
+			Old:     oldVal,
+			New:     newVal,
+		})
+	}
+
+	diffVariables(old, new, add)
+	diffOutputs(old, new, add)
+	diffVersionConstraints(old, new, add)
+	diffResources(old, new, add)
+
+	sort.SliceStable(d.Changes, func(i, j int) bool {
+		a, b := d.Changes[i], d.Changes[j]
+		if a.Bump != b.Bump {
+			return a.Bump.rank() > b.Bump.rank()
+		}
+		return a.Address < b.Address
+	})
+	d.Bump = BumpNone
+	for _, c := range d.Changes {
+		if c.Bump.rank() > d.Bump.rank() {
+			d.Bump = c.Bump
+		}
+	}
+	return d
+}
+
+type addChangeFunc func(kind ModuleChangeKind, addr string, bump SemverBump, oldVal, newVal interface{}, detail string, args ...interface{})
+
+func diffVariables(old, new *Module, add addChangeFunc) {
+	for _, name := range SortedKeysOfMap(old.Variables) {
+		if _, ok := new.Variables[name]; !ok {
+			add(VariableRemoved, "var."+name, BumpMajor, nil, nil, "Variable %q was removed, so callers that set it will fail.", name)
+		}
+	}
+	for _, name := range SortedKeysOfMap(new.Variables) {
+		addr := "var." + name
+		nv := new.Variables[name]
+		ov, ok := old.Variables[name]
+		if !ok {
+			if isRequired(nv) {
+				add(VariableAdded, addr, BumpMajor, nil, nil, "Required variable %q was added, so existing callers must now set it.", name)
+			} else {
+				add(VariableAdded, addr, BumpMinor, nil, nv.Default, "Optional variable %q was added.", name)
+			}
+			continue
+		}
+
+		if !variableTypesEqual(ov, nv) {
+			add(VariableTypeChanged, addr, BumpMajor, variableTypeString(ov), variableTypeString(nv), "The type of variable %q changed from %s to %s.", name, variableTypeString(ov), variableTypeString(nv))
+		}
+
+		switch {
+		case !isRequired(ov) && isRequired(nv):
+			add(VariableDefaultRemoved, addr, BumpMajor, ov.Default, nil, "Variable %q no longer has a default, so existing callers must now set it.", name)
+		case isRequired(ov) && !isRequired(nv):
+			add(VariableDefaultAdded, addr, BumpMinor, nil, nv.Default, "Variable %q now has a default and is optional.", name)
+		case !isRequired(nv) && !reflect.DeepEqual(ov.Default, nv.Default):
+			add(VariableDefaultChanged, addr, BumpMinor, ov.Default, nv.Default, "The default value of variable %q changed, which affects callers that don't set it.", name)
+		}
+
+		switch {
+		case !isTrue(ov.Sensitive) && isTrue(nv.Sensitive):
+			add(VariableSensitiveAdded, addr, BumpMinor, false, true, "Variable %q is now sensitive.", name)
+		case isTrue(ov.Sensitive) && !isTrue(nv.Sensitive):
+			add(VariableSensitiveRemoved, addr, BumpMinor, true, false, "Variable %q is no longer sensitive.", name)
+		}
+
+		if ov.Description != nv.Description {
+			add(VariableDescriptionChanged, addr, BumpPatch, ov.Description, nv.Description, "The description of variable %q changed.", name)
+		}
+	}
+}
+
+func variableTypeString(v *Variable) string {
+	if v.Type == "" {
+		return "any"
+	}
+	return v.Type
+}
+
+func diffOutputs(old, new *Module, add addChangeFunc) {
+	for _, name := range SortedKeysOfMap(old.Outputs) {
+		if _, ok := new.Outputs[name]; !ok {
+			add(OutputRemoved, "output."+name, BumpMajor, nil, nil, "Output %q was removed, so callers that use it will fail.", name)
+		}
+	}
+	for _, name := range SortedKeysOfMap(new.Outputs) {
+		addr := "output." + name
+		no := new.Outputs[name]
+		oo, ok := old.Outputs[name]
+		if !ok {
+			add(OutputAdded, addr, BumpMinor, nil, nil, "Output %q was added.", name)
+			continue
+		}
+
+		switch {
+		case !oo.Sensitive && no.Sensitive:
+			add(OutputSensitiveAdded, addr, BumpMajor, false, true, "Output %q is now sensitive, so callers that expose it in non-sensitive outputs will fail.", name)
+		case oo.Sensitive && !no.Sensitive:
+			add(OutputSensitiveRemoved, addr, BumpMinor, true, false, "Output %q is no longer sensitive.", name)
+		}
+
+		if oo.Value != no.Value {
+			// The expression alone doesn't show whether callers are
+			// affected; a change of type is reported separately below.
+			add(OutputValueChanged, addr, BumpMinor, oo.Value, no.Value, "The value of output %q changed, which may change its meaning for callers.", name)
+		}
+
+		if oo.Type != "" && no.Type != "" && oo.Type != no.Type {
+			add(OutputTypeChanged, addr, BumpMajor, oo.Type, no.Type, "The type of output %q changed from %s to %s.", name, oo.Type, no.Type)
+		}
+
+		if oo.Description != no.Description {
+			add(OutputDescriptionChanged, addr, BumpPatch, oo.Description, no.Description, "The description of output %q changed.", name)
+		}
+	}
+}
+
+func diffVersionConstraints(old, new *Module, add addChangeFunc) {
+	compare := func(addr, what string, oldConstraints, newConstraints []string, tightenedKind, loosenedKind ModuleChangeKind) {
+		oldStr, newStr := strings.Join(oldConstraints, ", "), strings.Join(newConstraints, ", ")
+		if oldStr == newStr {
+			return
+		}
+		tightened, loosened, err := compareVersionConstraints(oldConstraints, newConstraints)
+		switch {
+		case err != nil:
+			add(VersionConstraintsIncomparable, addr, BumpMajor, oldStr, newStr, "The version constraints for %s changed from %q to %q, but could not be compared: %s.", what, oldStr, newStr, err)
+		case tightened:
+			add(tightenedKind, addr, BumpMajor, oldStr, newStr, "The version constraints for %s were tightened from %q to %q, so some existing callers may no longer be able to use the module.", what, oldStr, newStr)
+		case loosened:
+			add(loosenedKind, addr, BumpMinor, oldStr, newStr, "The version constraints for %s were loosened from %q to %q.", what, oldStr, newStr)
+		}
+		// Otherwise the constraints were only rewritten, or changed in ways
+		// that only affect individual versions such as with "!=".
+	}
+
+	compare("terraform", "Terraform", old.RequiredCore, new.RequiredCore, RequiredVersionRaised, RequiredVersionLowered)
+
+	for _, name := range SortedKeysOfMap(old.RequiredProviders) {
+		if _, ok := new.RequiredProviders[name]; !ok {
+			add(ProviderRemoved, "provider."+name, BumpMinor, nil, nil, "Provider %q is no longer required.", name)
+		}
+	}
+	for _, name := range SortedKeysOfMap(new.RequiredProviders) {
+		addr := "provider." + name
+		np := new.RequiredProviders[name]
+		op, ok := old.RequiredProviders[name]
+		if !ok {
+			add(ProviderAdded, addr, BumpMajor, nil, np.Source, "Provider %q is now required, so callers may need to configure it.", name)
+			continue
+		}
+		if op.Source != np.Source {
+			add(ProviderSourceChanged, addr, BumpMajor, op.Source, np.Source, "The source of provider %q changed from %q to %q.", name, op.Source, np.Source)
+		}
+		compare(addr, fmt.Sprintf("provider %q", name), op.VersionConstraints, np.VersionConstraints, ProviderConstraintsTightened, ProviderConstraintsLoosened)
+	}
+}
+
+func diffResources(old, new *Module, add addChangeFunc) {
+	for _, key := range SortedKeysOfMap(old.ManagedResources) {
+		if _, ok := new.ManagedResources[key]; ok {
+			continue
+		}
+		if m, ok := movedFromObject(new.Moved, key); ok {
+			add(ResourceMoved, key, BumpMinor, m.From, m.To, "Resource %s was moved to %s.", m.From, m.To)
+		} else {
+			add(ResourceRemoved, key, BumpMajor, nil, nil, "Resource %s was removed without a moved block, so it will be destroyed.", key)
+		}
+	}
+	for _, key := range SortedKeysOfMap(new.ManagedResources) {
+		if _, ok := old.ManagedResources[key]; !ok {
+			add(ResourceAdded, key, BumpMinor, nil, nil, "Resource %s was added.", key)
+		}
+	}
+
+	for _, name := range SortedKeysOfMap(old.ModuleCalls) {
+		if _, ok := new.ModuleCalls[name]; ok {
+			continue
+		}
+		addr := "module." + name
+		if m, ok := movedFromObject(new.Moved, addr); ok {
+			add(ModuleCallMoved, addr, BumpMinor, m.From, m.To, "Module call %s was moved to %s.", m.From, m.To)
+		} else {
+			add(ModuleCallRemoved, addr, BumpMajor, nil, nil, "Module call %s was removed without a moved block, so its resources will be destroyed.", addr)
+		}
+	}
+	for _, name := range SortedKeysOfMap(new.ModuleCalls) {
+		if _, ok := old.ModuleCalls[name]; !ok {
+			add(ModuleCallAdded, "module."+name, BumpMinor, nil, nil, "Module call module.%s was added.", name)
+		}
+	}
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+// RenderDiffJSON writes the given diff as a JSON document.
+func RenderDiffJSON(w io.Writer, d *ModuleDiff) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// RenderDiffMarkdown writes the given diff as a markdown report, grouping
+// the changes by the version bump they require.
+func RenderDiffMarkdown(w io.Writer, d *ModuleDiff) error {
+	tmpl := template.New("diff")
+	tmpl.Funcs(TemplateFuncs())
+	template.Must(tmpl.Parse(diffMarkdownTemplate))
+
+	return tmpl.Execute(w, map[string]interface{}{
+		"Bump":  d.Bump,
+		"Major": d.changesWithBump(BumpMajor),
+		"Minor": d.changesWithBump(BumpMinor),
+		"Patch": d.changesWithBump(BumpPatch),
+	})
+}
+
+const diffMarkdownTemplate = `
+# Module Changes
+
+Suggested version bump: **{{ .Bump }}**
+
+{{- if .Major }}
+
+## Breaking Changes
+{{ range .Major }}
+* {{ tt .Address }}: {{ .Detail }}
+{{- end }}{{ end }}
+
+{{- if .Minor }}
+
+## Compatible Changes
+{{ range .Minor }}
+* {{ tt .Address }}: {{ .Detail }}
+{{- end }}{{ end }}
+
+{{- if .Patch }}
+
+## Other Changes
+{{ range .Patch }}
+* {{ tt .Address }}: {{ .Detail }}
+{{- end }}{{ end }}
+
+`
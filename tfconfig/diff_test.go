@@ -0,0 +1,131 @@
+package tfconfig
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestDiff(t *testing.T) {
+	oldModule, _ := LoadModule(filepath.Join("testdata", "diff-old"))
+	newModule, _ := LoadModule(filepath.Join("testdata", "diff-new"))
+	if oldModule == nil || newModule == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+	d := Diff(oldModule, newModule)
+
+	tests := map[string]func(io.Writer, *ModuleDiff) error{
+		".out.diff.json": RenderDiffJSON,
+		".out.diff.md":   RenderDiffMarkdown,
+	}
+	for suffix, render := range tests {
+		t.Run(suffix, func(t *testing.T) {
+			expected, err := ioutil.ReadFile(filepath.Join("testdata", "diff-new", "diff-new"+suffix))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := render(&buf, d); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(buf.String(), string(expected)); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	module, _ := LoadModule(filepath.Join("testdata", "basics"))
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+
+	d := Diff(module, module)
+	if d.Bump != BumpNone {
+		t.Errorf("wrong bump %q; want %q", d.Bump, BumpNone)
+	}
+	if len(d.Changes) != 0 {
+		t.Errorf("unexpected changes: %#v", d.Changes)
+	}
+}
+
+func TestDiffOutputValue(t *testing.T) {
+	oldModule := NewModule("old")
+	oldModule.Outputs["id"] = &Output{Name: "id", Value: "ibm_is_vpc.vpc.id", Type: "string"}
+	newModule := NewModule("new")
+	newModule.Outputs["id"] = &Output{Name: "id", Value: "ibm_is_vpc.main.id", Type: "string"}
+
+	// A rewritten expression of the same type isn't a breaking change.
+	d := Diff(oldModule, newModule)
+	if len(d.Changes) != 1 || d.Changes[0].Kind != OutputValueChanged || d.Bump != BumpMinor {
+		t.Errorf("wrong diff for changed value: %#v", d)
+	}
+
+	newModule.Outputs["id"].Type = "list(string)"
+	d = Diff(oldModule, newModule)
+	if breaking := d.Breaking(); len(breaking) != 1 || breaking[0].Kind != OutputTypeChanged {
+		t.Errorf("wrong diff for changed type: %#v", d)
+	}
+}
+
+func TestDiffOptionalAttributes(t *testing.T) {
+	oldModule := NewModule("old")
+	oldModule.Variables["settings"] = &Variable{Name: "settings", Type: "object({ a = optional(string) })"}
+	newModule := NewModule("new")
+	newModule.Variables["settings"] = &Variable{Name: "settings", Type: "object({\n  a = optional(string),\n})"}
+
+	// Types with optional attributes are compared by their source, ignoring
+	// spacing and trailing commas.
+	if d := Diff(oldModule, newModule); len(d.Changes) != 0 {
+		t.Errorf("wrong diff for reformatted type: %#v", d)
+	}
+
+	newModule.Variables["settings"].Type = "object({ a = optional(number), b = string })"
+	d := Diff(oldModule, newModule)
+	if len(d.Changes) != 1 || d.Changes[0].Kind != VariableTypeChanged || d.Bump != BumpMajor {
+		t.Errorf("wrong diff for changed type: %#v", d)
+	}
+}
+
+func TestCompareVersionConstraints(t *testing.T) {
+	tests := []struct {
+		old, new            []string
+		tightened, loosened bool
+	}{
+		{[]string{">= 1.0"}, []string{">= 1.0.0"}, false, false},
+		{[]string{">= 1.0"}, []string{">= 1.3"}, true, false},
+		{[]string{">= 1.3"}, []string{">= 1.0"}, false, true},
+		{nil, []string{">= 1.0"}, true, false},
+		{[]string{">= 1.0"}, nil, false, true},
+		{[]string{">= 1.0"}, []string{">= 1.0, < 2.0"}, true, false},
+		{[]string{"~> 1.2"}, []string{">= 1.2.0, < 2.0.0"}, false, false},
+		{[]string{"~> 1.2.3"}, []string{"~> 1.2"}, false, true},
+		{[]string{"~> 1.2"}, []string{"~> 2.0"}, true, true},
+		{[]string{"1.2.0"}, []string{"= 1.2.0"}, false, false},
+		{[]string{"> 1.2.0"}, []string{">= 1.2.0"}, false, true},
+	}
+
+	for _, test := range tests {
+		tightened, loosened, err := compareVersionConstraints(test.old, test.new)
+		if err != nil {
+			t.Errorf("%q -> %q: unexpected error: %s", test.old, test.new, err)
+			continue
+		}
+		if tightened != test.tightened || loosened != test.loosened {
+			t.Errorf("%q -> %q: got tightened=%t loosened=%t; want tightened=%t loosened=%t", test.old, test.new, tightened, loosened, test.tightened, test.loosened)
+		}
+	}
+
+	if _, _, err := compareVersionConstraints([]string{">= 1.0"}, []string{">= banana"}); err == nil {
+		t.Errorf("no error for invalid constraint")
+	}
+}
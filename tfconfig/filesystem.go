@@ -7,6 +7,7 @@ import (
 
 // FS represents a minimal filesystem implementation
 // See io/fs.FS in http://golang.org/s/draft-iofs-design
+// Use FromIOFS and ToIOFS to convert to and from io/fs.FS.
 type FS interface {
 	Open(name string) (File, error)
 	ReadFile(name string) ([]byte, error)
@@ -0,0 +1,168 @@
+package tfconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewGitFS returns an FS that reads the files of the local git repository
+// containing the given directory as they were at the given revision, such
+// as a commit hash, tag or branch name, without checking it out. Paths are
+// relative to the root of the repository.
+//
+// The repository is read with the git command, which must be installed,
+// and never contacted remotely. The names of all of the files at the
+// revision are listed at once, but the contents of each file are only read,
+// and then kept in memory, when it is first opened. Symbolic links and
+// submodules are ignored.
+func NewGitFS(repo, rev string) (FS, error) {
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return nil, fmt.Errorf("invalid revision %q", rev)
+	}
+	out, err := runGit(repo, nil, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %q", rev)
+	}
+	commit := strings.TrimSpace(string(out))
+
+	out, err = runGit(repo, nil, "show", "--no-patch", "--format=%ct", commit)
+	if err != nil {
+		return nil, err
+	}
+	seconds, _ := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	modTime := time.Unix(seconds, 0)
+
+	// Only the names and sizes of the files are listed up front, as a
+	// module is usually a small part of its repository. The contents of
+	// each file are read when it is first opened.
+	out, err = runGit(repo, nil, "ls-tree", "-r", "-z", "-l", "--full-tree", commit)
+	if err != nil {
+		return nil, err
+	}
+	fsys := newMemFs()
+	for _, line := range strings.Split(string(out), "\x00") {
+		// Each line is "<mode> <type> <object> <size>\t<path>".
+		tab := strings.IndexByte(line, '\t')
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(line[:tab])
+		if len(fields) != 4 || fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+		name := line[tab+1:]
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid path %q at revision %s", name, rev)
+		}
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s at revision %s: %s", name, rev, err)
+		}
+		mode := fs.FileMode(0644)
+		if fields[0] == "100755" {
+			mode = 0755
+		}
+		object := fields[2]
+		fsys.addLazyFile(name, size, func() ([]byte, error) {
+			return runGit(repo, nil, "cat-file", "blob", object)
+		}, mode, modTime)
+	}
+	return FromIOFS(fsys), nil
+}
+
+// OpenGitRevision returns an FS that reads the files of the local git
+// repository containing the given path as they were at the given revision,
+// as for NewGitFS, along with the directory within it that corresponds to
+// the path. The path need not exist in the working tree.
+func OpenGitRevision(path, rev string) (FS, string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The directory may have been removed or renamed since the revision, so
+	// we look for the repository from the nearest directory that exists.
+	existing, rest := abs, ""
+	for {
+		if info, err := os.Stat(existing); err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return nil, "", fmt.Errorf("%s is not in a git repository", path)
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	out, err := runGit(existing, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, "", fmt.Errorf("%s is not in a git repository", path)
+	}
+	top := strings.TrimSpace(string(out))
+
+	// Either path may contain symbolic links, such as a temporary directory
+	// on macOS.
+	if real, err := filepath.EvalSymlinks(existing); err == nil {
+		existing = real
+	}
+	if real, err := filepath.EvalSymlinks(top); err == nil {
+		top = real
+	}
+	dir, err := filepath.Rel(top, filepath.Join(existing, rest))
+	if err != nil || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return nil, "", fmt.Errorf("%s is not in a git repository", path)
+	}
+
+	fsys, err := NewGitFS(top, rev)
+	if err != nil {
+		return nil, "", err
+	}
+	return fsys, dir, nil
+}
+
+// LoadModuleAtRevision is a variant of LoadModule that reads the module in
+// the given directory as it was at the given revision of the local git
+// repository containing it, as for OpenGitRevision. Source positions are
+// relative to the root of the repository.
+func LoadModuleAtRevision(path, rev string) (*Module, Diagnostics) {
+	fsys, dir, err := OpenGitRevision(path, rev)
+	if err != nil {
+		module := NewModule(path)
+		diags := Diagnostics{
+			{
+				Severity: DiagError,
+				Summary:  "Failed to read module revision",
+				Code:     DiagCodeReadRevision,
+				Detail:   fmt.Sprintf("Module %s cannot be read at revision %s: %s.", path, rev, err),
+			},
+		}
+		module.init(diags)
+		return module, diags
+	}
+	return LoadModuleFromFilesystem(fsys, dir)
+}
+
+// runGit runs the git command in the given directory with the given
+// arguments and standard input, returning its standard output.
+func runGit(dir string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], err)
+	}
+	return out, nil
+}
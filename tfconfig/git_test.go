@@ -0,0 +1,92 @@
+package tfconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadModuleAtRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s\n%s", args, err, out)
+		}
+	}
+	write := func(name, src string) {
+		t.Helper()
+		name = filepath.Join(repo, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "--quiet")
+	write("modules/vpc/main.tf", "variable \"name\" {}\n\noutput \"id\" {\n  value = var.name\n}\n")
+	write("README.md", "# Modules\n")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1.4.0")
+
+	write("modules/vpc/main.tf", "variable \"name\" {}\n\nvariable \"cidr\" {}\n")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "v2")
+	// Uncommitted changes are ignored.
+	write("modules/vpc/main.tf", "variable \"uncommitted\" {}\n")
+
+	old, diags := LoadModuleAtRevision(filepath.Join(repo, "modules", "vpc"), "v1.4.0")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	if got, want := SortedKeysOfMap(old.Outputs), []string{"id"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("wrong outputs at v1.4.0 %v; want %v", got, want)
+	}
+	if got, want := old.Variables["name"].Pos.Filename, filepath.Join("modules", "vpc", "main.tf"); got != want {
+		t.Errorf("wrong filename %q; want %q", got, want)
+	}
+
+	new, diags := LoadModuleAtRevision(filepath.Join(repo, "modules", "vpc"), "HEAD")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	if got, want := SortedKeysOfMap(new.Variables), []string{"cidr", "name"}; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("wrong variables at HEAD %v; want %v", got, want)
+	}
+	if d := Diff(old, new); d.Bump != BumpMajor {
+		t.Errorf("wrong bump %q; want %q", d.Bump, BumpMajor)
+	}
+
+	fsys, err := NewGitFS(repo, "v1.4.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(ToIOFS(fsys), "README.md", "modules/vpc/main.tf"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Directories removed since the revision can still be loaded.
+	if err := os.RemoveAll(filepath.Join(repo, "modules")); err != nil {
+		t.Fatal(err)
+	}
+	if _, diags := LoadModuleAtRevision(filepath.Join(repo, "modules", "vpc"), "v1.4.0"); diags.HasErrors() {
+		t.Errorf("unexpected errors loading a removed directory: %s", diags)
+	}
+
+	for _, rev := range []string{"v9.9.9", "--all", ""} {
+		_, diags := LoadModuleAtRevision(repo, rev)
+		if len(diags) != 1 || diags[0].Code != DiagCodeReadRevision {
+			t.Errorf("wrong diagnostics for revision %q: %#v", rev, diags)
+		}
+	}
+}
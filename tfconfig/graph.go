@@ -0,0 +1,232 @@
+package tfconfig
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Graph is a directed graph of the references between the objects declared
+// in a single module. Edges point in the direction that data flows, from the
+// referenced object to the object that refers to it.
+type Graph struct {
+	Nodes []*GraphNode `json:"nodes"`
+	Edges []*GraphEdge `json:"edges"`
+}
+
+// GraphNode is an object declared in a module.
+type GraphNode struct {
+	// ID is the address of the object within the module, such as
+	// "var.name", "local.prefix", "ibm_is_vpc.vpc", "data.ibm_is_image.os",
+	// "module.dns" or "output.vpc_id".
+	ID   string        `json:"id"`
+	Kind GraphNodeKind `json:"kind"`
+}
+
+// GraphNodeKind describes what kind of object a GraphNode represents.
+type GraphNodeKind string
+
+const (
+	GraphNodeVariable   GraphNodeKind = "variable"
+	GraphNodeLocal      GraphNodeKind = "local"
+	GraphNodeData       GraphNodeKind = "data"
+	GraphNodeResource   GraphNodeKind = "resource"
+	GraphNodeModuleCall GraphNodeKind = "module"
+	GraphNodeOutput     GraphNodeKind = "output"
+)
+
+// GraphEdge is a dependency of one object on another.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Label is the name of the child module output that is referred to,
+	// for edges from module calls.
+	Label string `json:"label,omitempty"`
+
+	// DependsOn is true for dependencies declared explicitly using
+	// depends_on, rather than implied by a reference.
+	DependsOn bool `json:"depends_on,omitempty"`
+}
+
+var graphNodeKindOrder = map[GraphNodeKind]int{
+	GraphNodeVariable:   0,
+	GraphNodeLocal:      1,
+	GraphNodeData:       2,
+	GraphNodeResource:   3,
+	GraphNodeModuleCall: 4,
+	GraphNodeOutput:     5,
+}
+
+// NewGraph builds the reference graph of the given module, using the
+// references recorded for each object when it was loaded.
+//
+// References to objects that the module doesn't declare, such as resources
+// that were removed, are not included. Each reference to an output of a
+// child module becomes an edge from the module call, labelled with the name
+// of the output.
+func NewGraph(module *Module) *Graph {
+	g := &Graph{}
+	nodes := make(map[string]bool)
+	addNode := func(id string, kind GraphNodeKind) {
+		nodes[id] = true
+		g.Nodes = append(g.Nodes, &GraphNode{ID: id, Kind: kind})
+	}
+	for name := range module.Variables {
+		addNode("var."+name, GraphNodeVariable)
+	}
+	for name := range module.Locals {
+		addNode("local."+name, GraphNodeLocal)
+	}
+	for key := range module.DataResources {
+		addNode(key, GraphNodeData)
+	}
+	for key := range module.ManagedResources {
+		addNode(key, GraphNodeResource)
+	}
+	for name := range module.ModuleCalls {
+		addNode("module."+name, GraphNodeModuleCall)
+	}
+	for name := range module.Outputs {
+		addNode("output."+name, GraphNodeOutput)
+	}
+
+	edges := make(map[GraphEdge]bool)
+	addEdges := func(to string, refs []string, dependsOn bool) {
+		for _, ref := range refs {
+			from, label := ref, ""
+			if strings.HasPrefix(ref, "module.") {
+				parts := strings.SplitN(ref, ".", 3)
+				from = parts[0] + "." + parts[1]
+				if len(parts) == 3 {
+					label = parts[2]
+				}
+			}
+			if !nodes[from] || from == to {
+				continue
+			}
+			edge := GraphEdge{From: from, To: to, Label: label, DependsOn: dependsOn}
+			if !edges[edge] {
+				edges[edge] = true
+				g.Edges = append(g.Edges, &edge)
+			}
+		}
+	}
+	for name, l := range module.Locals {
+		addEdges("local."+name, l.References, false)
+	}
+	for _, resources := range []map[string]*Resource{module.DataResources, module.ManagedResources} {
+		for key, r := range resources {
+			addEdges(key, r.References, false)
+			addEdges(key, r.DependsOn, true)
+		}
+	}
+	for name, mc := range module.ModuleCalls {
+		addEdges("module."+name, mc.References, false)
+		addEdges("module."+name, mc.DependsOn, true)
+	}
+	for name, o := range module.Outputs {
+		addEdges("output."+name, o.References, false)
+		addEdges("output."+name, o.DependsOn, true)
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		a, b := g.Nodes[i], g.Nodes[j]
+		if a.Kind != b.Kind {
+			return graphNodeKindOrder[a.Kind] < graphNodeKindOrder[b.Kind]
+		}
+		return a.ID < b.ID
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		switch {
+		case a.From != b.From:
+			return a.From < b.From
+		case a.To != b.To:
+			return a.To < b.To
+		case a.Label != b.Label:
+			return a.Label < b.Label
+		default:
+			return !a.DependsOn && b.DependsOn
+		}
+	})
+	return g
+}
+
+// RenderGraphDOT writes the given graph in the Graphviz DOT language.
+func RenderGraphDOT(w io.Writer, g *Graph) error {
+	shapes := map[GraphNodeKind]string{
+		GraphNodeVariable:   "ellipse",
+		GraphNodeLocal:      "ellipse",
+		GraphNodeData:       "cylinder",
+		GraphNodeResource:   "box",
+		GraphNodeModuleCall: "component",
+		GraphNodeOutput:     "ellipse",
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph {")
+	fmt.Fprintln(bw, "  rankdir = \"LR\";")
+	for _, n := range g.Nodes {
+		style := ""
+		if n.Kind == GraphNodeLocal {
+			style = ", style=dashed"
+		} else if n.Kind == GraphNodeOutput {
+			style = ", peripheries=2"
+		}
+		fmt.Fprintf(bw, "  %s [shape=%s%s];\n", strconv.Quote(n.ID), shapes[n.Kind], style)
+	}
+	for _, e := range g.Edges {
+		var attrs []string
+		if e.Label != "" {
+			attrs = append(attrs, "label="+strconv.Quote(e.Label))
+		}
+		if e.DependsOn {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(bw, "  %s -> %s [%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(bw, "  %s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To))
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// RenderGraphMermaid writes the given graph as a Mermaid flowchart, which
+// can be embedded in markdown documents rendered by GitHub.
+func RenderGraphMermaid(w io.Writer, g *Graph) error {
+	shapes := map[GraphNodeKind][2]string{
+		GraphNodeVariable:   {"([", "])"},
+		GraphNodeLocal:      {"([", "])"},
+		GraphNodeData:       {"[(", ")]"},
+		GraphNodeResource:   {"[", "]"},
+		GraphNodeModuleCall: {"[[", "]]"},
+		GraphNodeOutput:     {"((", "))"},
+	}
+
+	ids := make(map[string]string, len(g.Nodes))
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart LR")
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		shape := shapes[n.Kind]
+		fmt.Fprintf(bw, "  %s%s\"%s\"%s\n", id, shape[0], n.ID, shape[1])
+	}
+	for _, e := range g.Edges {
+		switch {
+		case e.DependsOn:
+			fmt.Fprintf(bw, "  %s -. depends_on .-> %s\n", ids[e.From], ids[e.To])
+		case e.Label != "":
+			fmt.Fprintf(bw, "  %s -- %s --> %s\n", ids[e.From], e.Label, ids[e.To])
+		default:
+			fmt.Fprintf(bw, "  %s --> %s\n", ids[e.From], ids[e.To])
+		}
+	}
+	return bw.Flush()
+}
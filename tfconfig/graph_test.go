@@ -0,0 +1,150 @@
+package tfconfig
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-test/deep"
+)
+
+func TestNewGraph(t *testing.T) {
+	module, _ := LoadModule(filepath.Join("testdata", "references"))
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+
+	g := NewGraph(module)
+
+	var got []GraphEdge
+	for _, e := range g.Edges {
+		got = append(got, *e)
+	}
+	want := []GraphEdge{
+		{From: "data.ibm_resource_group.group", To: "ibm_is_vpc.vpc"},
+		{From: "ibm_is_subnet.subnet", To: "output.dns_zone", DependsOn: true},
+		{From: "ibm_is_subnet.subnet", To: "output.subnet_ids"},
+		{From: "ibm_is_vpc.vpc", To: "ibm_is_subnet.subnet"},
+		{From: "ibm_is_vpc.vpc", To: "module.dns"},
+		{From: "local.prefix", To: "ibm_is_subnet.subnet"},
+		{From: "local.prefix", To: "ibm_is_vpc.vpc"},
+		{From: "module.dns", To: "ibm_is_subnet.subnet", DependsOn: true},
+		{From: "module.dns", To: "output.dns_zone", Label: "zone_id"},
+		{From: "var.name", To: "local.prefix"},
+		{From: "var.zone", To: "ibm_is_subnet.subnet"},
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
+
+func TestNewGraphLegacySyntax(t *testing.T) {
+	g := NewGraph(loadLegacyTestModule(t))
+
+	var got []GraphEdge
+	for _, e := range g.Edges {
+		got = append(got, *e)
+	}
+	want := []GraphEdge{
+		{From: "ibm_is_vpc.vpc", To: "module.dns"},
+		{From: "ibm_is_vpc.vpc", To: "output.vpc"},
+		{From: "local.name", To: "ibm_is_vpc.vpc"},
+		{From: "module.dns", To: "output.vpc", DependsOn: true},
+		{From: "var.foo", To: "local.name"},
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
+
+// loadLegacyTestModule loads a module that only the legacy HCL parser can
+// read, because its block braces are on the line after their labels.
+func loadLegacyTestModule(t *testing.T) *Module {
+	t.Helper()
+	src := `
+variable "foo"
+{
+  default = "foo"
+}
+
+variable "region"
+{
+}
+
+variable "unused"
+{
+}
+
+provider "ibm"
+{
+  region = "${var.region}"
+}
+
+locals
+{
+  name = "${var.foo}-vpc"
+}
+
+resource "ibm_is_vpc" "vpc"
+{
+  name = "${local.name}"
+}
+
+module "dns"
+{
+  source = "./dns"
+  vpc    = "${ibm_is_vpc.vpc.id}"
+}
+
+output "vpc"
+{
+  value      = "${ibm_is_vpc.vpc.id}"
+  depends_on = ["module.dns"]
+}
+`
+	module, diags := LoadModuleFromIOFS(fstest.MapFS{"main.tf": {Data: []byte(src)}}, ".")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	return module
+}
+
+func TestRenderGraph(t *testing.T) {
+	path := filepath.Join("testdata", "references")
+	module, _ := LoadModule(path)
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+	g := NewGraph(module)
+
+	tests := map[string]func(io.Writer, *Graph) error{
+		".out.dot": RenderGraphDOT,
+		".out.mmd": RenderGraphMermaid,
+	}
+	for suffix, render := range tests {
+		t.Run(suffix, func(t *testing.T) {
+			expected, err := ioutil.ReadFile(filepath.Join(path, "references"+suffix))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := render(&buf, g); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(buf.String(), string(expected)); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package tfconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// HTMLOptions customizes the output of RenderHTMLSite.
+type HTMLOptions struct {
+	// SourceURL, if set, is a base URL that source file paths relative to
+	// the root module are appended to in order to link each definition to
+	// its source, such as "https://github.com/org/repo/blob/main/". A
+	// "#L<line>" fragment is added to each link.
+	SourceURL string
+}
+
+// HTMLSearchEntry is a single entry in the search index written by
+// RenderHTMLSite.
+type HTMLSearchEntry struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Module      string `json:"module"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+}
+
+// HTMLSearchIndexFile is the name of the search index file written by
+// RenderHTMLSite, alongside the pages.
+const HTMLSearchIndexFile = "search-index.json"
+
+// WriteHTMLSite renders the given module tree with RenderHTMLSite and writes
+// the result into the given directory, creating it if necessary.
+func WriteHTMLSite(outDir string, tree *ModuleTree, opts HTMLOptions) error {
+	files, err := RenderHTMLSite(tree, opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	for _, name := range SortedKeysOfMap(files) {
+		if err := ioutil.WriteFile(filepath.Join(outDir, name), files[name], 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderHTMLSite renders a static HTML documentation site for the given
+// module tree, returning the content of each file keyed by filename.
+//
+// There is one page per module, named index.html for the root module and
+// after the module address otherwise. Each definition on a page has an
+// anchor named after its address within the module, such as "var.region",
+// and another named after its source position, such as "main.tf:12". Module
+// calls link to the page of the called module and each child page links back
+// to its call. A search index of every definition is included as
+// HTMLSearchIndexFile.
+func RenderHTMLSite(tree *ModuleTree, opts HTMLOptions) (map[string][]byte, error) {
+	tmpl, err := template.New("page").Funcs(template.FuncMap(TemplateFuncs())).Parse(htmlPageTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	rootDir := tree.Module.Path
+	files := make(map[string][]byte)
+	index := []HTMLSearchEntry{}
+
+	var visit func(t *ModuleTree, parent *htmlLink) error
+	visit = func(t *ModuleTree, parent *htmlLink) error {
+		page := newHTMLPage(t, parent, rootDir, opts)
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, page); err != nil {
+			return fmt.Errorf("failed to render page for %s: %s", page.Title, err)
+		}
+		files[page.FileName] = buf.Bytes()
+		index = append(index, page.searchEntries()...)
+
+		for _, name := range SortedKeysOfMap(t.Children) {
+			back := &htmlLink{
+				Text: page.Title,
+				Href: page.FileName + "#module." + name,
+			}
+			if err := visit(t.Children[name], back); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(tree, nil); err != nil {
+		return nil, err
+	}
+
+	src, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files[HTMLSearchIndexFile] = append(src, '\n')
+	return files, nil
+}
+
+// htmlPageFileName returns the name of the page for the module with the
+// given address.
+func htmlPageFileName(address string) string {
+	if address == "" {
+		return "index.html"
+	}
+	return address + ".html"
+}
+
+type htmlLink struct {
+	Text string
+	Href string
+}
+
+type htmlPage struct {
+	Title    string
+	FileName string
+	Module   *Module
+	Parent   *htmlLink
+
+	Variables        []*htmlRow
+	Outputs          []*htmlRow
+	ManagedResources []*htmlRow
+	DataResources    []*htmlRow
+	ModuleCalls      []*htmlRow
+}
+
+// htmlRow is a single definition on a page. Exactly one of the
+// definition fields is set.
+type htmlRow struct {
+	ID      string
+	Pos     string
+	PosID   string
+	PosHref string
+
+	Variable *Variable
+	Output   *Output
+	Resource *Resource
+	Call     *ModuleCall
+
+	// Child is the page of the called module, for module calls that were
+	// resolved.
+	Child *htmlLink
+}
+
+func newHTMLPage(t *ModuleTree, parent *htmlLink, rootDir string, opts HTMLOptions) *htmlPage {
+	page := &htmlPage{
+		Title:    t.Address,
+		FileName: htmlPageFileName(t.Address),
+		Module:   t.Module,
+		Parent:   parent,
+	}
+	if page.Title == "" {
+		page.Title = "Root module"
+	}
+
+	seenPos := make(map[string]bool)
+	newRow := func(id string, pos *SourcePos) *htmlRow {
+		row := &htmlRow{ID: id}
+		if pos == nil || pos.Filename == "" {
+			return row
+		}
+		filename := pos.Filename
+		if rel, err := filepath.Rel(rootDir, filename); err == nil {
+			filename = rel
+		}
+		filename = filepath.ToSlash(filename)
+		row.Pos = fmt.Sprintf("%s:%d", filename, pos.Line)
+		if !seenPos[row.Pos] {
+			seenPos[row.Pos] = true
+			row.PosID = row.Pos
+		}
+		if opts.SourceURL != "" {
+			row.PosHref = fmt.Sprintf("%s%s#L%d", opts.SourceURL, filename, pos.Line)
+		} else {
+			row.PosHref = "#" + row.Pos
+		}
+		return row
+	}
+
+	for _, k := range SortedKeysOfMap(t.Module.Variables) {
+		v := t.Module.Variables[k]
+		row := newRow("var."+k, v.Pos)
+		row.Variable = v
+		page.Variables = append(page.Variables, row)
+	}
+	for _, k := range SortedKeysOfMap(t.Module.Outputs) {
+		o := t.Module.Outputs[k]
+		row := newRow("output."+k, o.Pos)
+		row.Output = o
+		page.Outputs = append(page.Outputs, row)
+	}
+	for _, k := range SortedKeysOfMap(t.Module.ManagedResources) {
+		r := t.Module.ManagedResources[k]
+		row := newRow(k, &r.Pos)
+		row.Resource = r
+		page.ManagedResources = append(page.ManagedResources, row)
+	}
+	for _, k := range SortedKeysOfMap(t.Module.DataResources) {
+		r := t.Module.DataResources[k]
+		row := newRow(k, &r.Pos)
+		row.Resource = r
+		page.DataResources = append(page.DataResources, row)
+	}
+	for _, k := range SortedKeysOfMap(t.Module.ModuleCalls) {
+		c := t.Module.ModuleCalls[k]
+		row := newRow("module."+k, &c.Pos)
+		row.Call = c
+		if child, ok := t.Children[k]; ok {
+			row.Child = &htmlLink{
+				Text: child.Address,
+				Href: htmlPageFileName(child.Address),
+			}
+		}
+		page.ModuleCalls = append(page.ModuleCalls, row)
+	}
+	return page
+}
+
+func (p *htmlPage) searchEntries() []HTMLSearchEntry {
+	var ret []HTMLSearchEntry
+	add := func(rows []*htmlRow, kind string, description func(*htmlRow) string) {
+		for _, row := range rows {
+			ret = append(ret, HTMLSearchEntry{
+				Name:        row.ID,
+				Kind:        kind,
+				Module:      p.Title,
+				Description: description(row),
+				URL:         p.FileName + "#" + row.ID,
+			})
+		}
+	}
+	none := func(*htmlRow) string { return "" }
+	add(p.Variables, "variable", func(r *htmlRow) string { return r.Variable.Description })
+	add(p.Outputs, "output", func(r *htmlRow) string { return r.Output.Description })
+	add(p.ManagedResources, "resource", none)
+	add(p.DataResources, "data", none)
+	add(p.ModuleCalls, "module", func(r *htmlRow) string { return r.Call.Source })
+	return ret
+}
+
+const htmlPageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+code, .pos { font-family: monospace; }
+.pos { color: #666; }
+</style>
+</head>
+<body>
+<nav><a href="index.html">Root module</a>{{ if .Parent }} &middot; called from <a href="{{ .Parent.Href }}">{{ .Parent.Text }}</a>{{ end }}</nav>
+<h1>{{ .Title }}</h1>
+<p>Source: <code>{{ .Module.Path }}</code></p>
+{{- if .Variables }}
+<h2 id="variables">Input Variables</h2>
+<table>
+<tr><th>Name</th><th>Type</th><th>Default</th><th>Required</th><th>Description</th><th>Defined at</th></tr>
+{{- range .Variables }}
+<tr id="{{ .ID }}"><td><code>{{ .Variable.Name }}</code></td><td><code>{{ .Variable.Type }}</code></td><td>{{ if not (isTrue .Variable.Required) }}<code>{{ json .Variable.Default }}</code>{{ end }}</td><td>{{ if isTrue .Variable.Required }}yes{{ else }}no{{ end }}</td><td>{{ .Variable.Description }}</td><td>{{ template "pos" . }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .Outputs }}
+<h2 id="outputs">Output Values</h2>
+<table>
+<tr><th>Name</th><th>Description</th><th>Sensitive</th><th>Defined at</th></tr>
+{{- range .Outputs }}
+<tr id="{{ .ID }}"><td><code>{{ .Output.Name }}</code></td><td>{{ .Output.Description }}</td><td>{{ if .Output.Sensitive }}yes{{ else }}no{{ end }}</td><td>{{ template "pos" . }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .ManagedResources }}
+<h2 id="resources">Managed Resources</h2>
+<table>
+<tr><th>Address</th><th>Provider</th><th>Defined at</th></tr>
+{{- range .ManagedResources }}
+<tr id="{{ .ID }}"><td><code>{{ .ID }}</code></td><td><code>{{ .Resource.Provider.Name }}</code></td><td>{{ template "pos" . }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .DataResources }}
+<h2 id="data-resources">Data Resources</h2>
+<table>
+<tr><th>Address</th><th>Provider</th><th>Defined at</th></tr>
+{{- range .DataResources }}
+<tr id="{{ .ID }}"><td><code>{{ .ID }}</code></td><td><code>{{ .Resource.Provider.Name }}</code></td><td>{{ template "pos" . }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .ModuleCalls }}
+<h2 id="module-calls">Child Modules</h2>
+<table>
+<tr><th>Name</th><th>Source</th><th>Version</th><th>Defined at</th></tr>
+{{- range .ModuleCalls }}
+<tr id="{{ .ID }}"><td>{{ if .Child }}<a href="{{ .Child.Href }}"><code>{{ .Call.Name }}</code></a>{{ else }}<code>{{ .Call.Name }}</code>{{ end }}</td><td><code>{{ .Call.Source }}</code></td><td>{{ .Call.Version }}</td><td>{{ template "pos" . }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- if .Module.Diagnostics }}
+<h2 id="problems">Problems</h2>
+<ul>
+{{- range .Module.Diagnostics }}
+<li>{{ .Summary }}{{ if .Pos }} (at <code>{{ .Pos.Filename }}</code> line {{ .Pos.Line }}){{ end }}{{ if .Detail }}: {{ .Detail }}{{ end }}</li>
+{{- end }}
+</ul>
+{{- end }}
+</body>
+</html>
+{{ define "pos" }}{{ if .Pos }}<a class="pos"{{ if .PosID }} id="{{ .PosID }}"{{ end }} href="{{ .PosHref }}">{{ .Pos }}</a>{{ end }}{{ end }}`
@@ -0,0 +1,71 @@
+package tfconfig
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderHTMLSite(t *testing.T) {
+	tree, _ := LoadModuleTree(filepath.Join("testdata", "module-tree"))
+	files, err := RenderHTMLSite(tree, HTMLOptions{SourceURL: "https://example.com/repo/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{
+		"index.html",
+		"module.network.html",
+		"module.network.module.subnet.html",
+		"module.storage.html",
+		HTMLSearchIndexFile,
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing file %s", name)
+		}
+	}
+	if len(files) != 5 {
+		t.Errorf("wrong number of files %d; want 5", len(files))
+	}
+
+	contains := map[string][]string{
+		"index.html": {
+			`<tr id="module.network"><td><a href="module.network.html"><code>network</code></a>`,
+			`<tr id="module.missing"><td><code>missing</code></td>`,
+			`<a class="pos" id="main.tf:1" href="https://example.com/repo/main.tf#L1">main.tf:1</a>`,
+		},
+		"module.network.html": {
+			`called from <a href="index.html#module.network">Root module</a>`,
+			`<a href="module.network.module.subnet.html"><code>subnet</code></a>`,
+			`href="https://example.com/repo/network/main.tf#L5">network/main.tf:5</a>`,
+		},
+		"module.network.module.subnet.html": {
+			`called from <a href="module.network.html#module.subnet">module.network</a>`,
+		},
+	}
+	for name, wants := range contains {
+		for _, want := range wants {
+			if !strings.Contains(string(files[name]), want) {
+				t.Errorf("%s does not contain %q", name, want)
+			}
+		}
+	}
+
+	var index []HTMLSearchEntry
+	if err := json.Unmarshal(files[HTMLSearchIndexFile], &index); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, entry := range index {
+		if entry.Name == "output.bucket" {
+			found = true
+			if entry.URL != "module.storage.html#output.bucket" || entry.Description != "Name of the bucket" {
+				t.Errorf("wrong search entry %#v", entry)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("search index has no entry for output.bucket")
+	}
+}
@@ -0,0 +1,64 @@
+package tfconfig
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// DefaultBeginMarker and DefaultEndMarker are the comments that delimit the
+// generated region of a document when using InjectBetweenMarkers.
+const (
+	DefaultBeginMarker = "<!-- BEGIN_TF_DOCS -->"
+	DefaultEndMarker   = "<!-- END_TF_DOCS -->"
+)
+
+// InjectBetweenMarkers returns a copy of the given document with the region
+// between the given begin and end markers replaced by the given content.
+//
+// The markers themselves and everything outside of them are preserved
+// byte-for-byte, so that injecting the same content twice produces the same
+// result. The content is written on the lines between the markers. It is an
+// error if the document doesn't contain exactly one pair of markers in the
+// correct order, or if one marker contains the other.
+func InjectBetweenMarkers(doc, content []byte, begin, end string) ([]byte, error) {
+	if begin == "" || end == "" {
+		return nil, fmt.Errorf("begin and end markers must not be empty")
+	}
+	beginMarker, endMarker := []byte(begin), []byte(end)
+	// Each marker is counted on its own, so neither may be found within
+	// the other.
+	if bytes.Contains(endMarker, beginMarker) || bytes.Contains(beginMarker, endMarker) {
+		return nil, fmt.Errorf("begin marker %q and end marker %q must not contain one another", begin, end)
+	}
+
+	switch bytes.Count(doc, beginMarker) {
+	case 0:
+		return nil, fmt.Errorf("begin marker %q not found", begin)
+	case 1:
+	default:
+		return nil, fmt.Errorf("begin marker %q found more than once", begin)
+	}
+	switch bytes.Count(doc, endMarker) {
+	case 0:
+		return nil, fmt.Errorf("end marker %q not found", end)
+	case 1:
+	default:
+		return nil, fmt.Errorf("end marker %q found more than once", end)
+	}
+
+	start := bytes.Index(doc, beginMarker) + len(beginMarker)
+	stop := bytes.Index(doc, endMarker)
+	if stop < start {
+		return nil, fmt.Errorf("end marker %q appears before begin marker %q", end, begin)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(doc[:start])
+	buf.WriteByte('\n')
+	if content = bytes.Trim(content, "\n"); len(content) > 0 {
+		buf.Write(content)
+		buf.WriteByte('\n')
+	}
+	buf.Write(doc[stop:])
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,89 @@
+package tfconfig
+
+import (
+	"testing"
+)
+
+func TestInjectBetweenMarkers(t *testing.T) {
+	tests := map[string]struct {
+		doc     string
+		content string
+		want    string
+		wantErr bool
+	}{
+		"replace": {
+			doc:     "# Title\n\n<!-- BEGIN_TF_DOCS -->\nold docs\n<!-- END_TF_DOCS -->\n\nFooter\n",
+			content: "\n# Module `a`\n",
+			want:    "# Title\n\n<!-- BEGIN_TF_DOCS -->\n# Module `a`\n<!-- END_TF_DOCS -->\n\nFooter\n",
+		},
+		"empty region": {
+			doc:     "<!-- BEGIN_TF_DOCS --><!-- END_TF_DOCS -->",
+			content: "docs",
+			want:    "<!-- BEGIN_TF_DOCS -->\ndocs\n<!-- END_TF_DOCS -->",
+		},
+		"empty content": {
+			doc:     "<!-- BEGIN_TF_DOCS -->\nold docs\n<!-- END_TF_DOCS -->\n",
+			content: "",
+			want:    "<!-- BEGIN_TF_DOCS -->\n<!-- END_TF_DOCS -->\n",
+		},
+		"missing begin": {
+			doc:     "<!-- END_TF_DOCS -->\n",
+			wantErr: true,
+		},
+		"missing end": {
+			doc:     "<!-- BEGIN_TF_DOCS -->\n",
+			wantErr: true,
+		},
+		"reversed": {
+			doc:     "<!-- END_TF_DOCS -->\n<!-- BEGIN_TF_DOCS -->\n",
+			wantErr: true,
+		},
+		"duplicate": {
+			doc:     "<!-- BEGIN_TF_DOCS -->\n<!-- END_TF_DOCS -->\n<!-- BEGIN_TF_DOCS -->\n<!-- END_TF_DOCS -->\n",
+			wantErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := InjectBetweenMarkers([]byte(test.doc), []byte(test.content), DefaultBeginMarker, DefaultEndMarker)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("wrong result\ngot:  %q\nwant: %q", got, test.want)
+			}
+
+			again, err := InjectBetweenMarkers(got, []byte(test.content), DefaultBeginMarker, DefaultEndMarker)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(got) {
+				t.Errorf("injecting twice is not stable\ngot:  %q\nwant: %q", again, got)
+			}
+		})
+	}
+}
+
+func TestInjectBetweenMarkersOverlapping(t *testing.T) {
+	tests := map[string][2]string{
+		"identical":       {"<!-- DOCS -->", "<!-- DOCS -->"},
+		"begin in end":    {"<!-- DOCS -->", "<!-- DOCS --><!-- END -->"},
+		"end in begin":    {"<!-- DOCS START --><!-- END -->", "<!-- END -->"},
+		"begin is prefix": {"<!-- DOCS", "<!-- DOCS END -->"},
+	}
+	for name, markers := range tests {
+		t.Run(name, func(t *testing.T) {
+			doc := "before\n" + markers[0] + "\nold\n" + markers[1] + "\nafter\n"
+			if got, err := InjectBetweenMarkers([]byte(doc), []byte("new"), markers[0], markers[1]); err == nil {
+				t.Fatalf("expected error, got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package tfconfig
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// FromIOFS returns an FS that reads from the given standard library file
+// system, such as an embed.FS, an os.DirFS, a zip.Reader or an fstest.MapFS.
+//
+// The paths given to the returned FS use the separator of the operating
+// system, as the paths built by this package do, and are relative to the
+// root of the given file system. Paths that are absolute or that refer to
+// a parent of the root are invalid.
+func FromIOFS(fsys fs.FS) FS {
+	return &ioFs{fsys: fsys}
+}
+
+// ToIOFS returns a standard library file system that reads from the given
+// FS, translating the slash-separated paths of the io/fs package into paths
+// with the separator of the operating system.
+//
+// The returned file system also implements fs.ReadFileFS and fs.ReadDirFS.
+func ToIOFS(fsys FS) fs.FS {
+	return &fromFs{fsys: fsys}
+}
+
+// LoadModuleFromIOFS is a variant of LoadModule that reads the module in the
+// given directory of the given standard library file system.
+func LoadModuleFromIOFS(fsys fs.FS, dir string) (*Module, Diagnostics) {
+	return LoadModuleFromFilesystem(FromIOFS(fsys), dir)
+}
+
+type ioFs struct {
+	fsys fs.FS
+}
+
+func (f *ioFs) Open(name string) (File, error) {
+	name, err := ioFsPath("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.fsys.Open(name)
+}
+
+func (f *ioFs) ReadFile(name string) ([]byte, error) {
+	name, err := ioFsPath("read", name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(f.fsys, name)
+}
+
+func (f *ioFs) ReadDir(dirname string) ([]os.FileInfo, error) {
+	dirname, err := ioFsPath("readdir", dirname)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := fs.ReadDir(f.fsys, dirname)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// ioFsPath converts the given path, with the separator of the operating
+// system, into a path valid for the io/fs package.
+func ioFsPath(op, name string) (string, error) {
+	ret := path.Clean(filepath.ToSlash(name))
+	if !fs.ValidPath(ret) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return ret, nil
+}
+
+type fromFs struct {
+	fsys FS
+}
+
+func (f *fromFs) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return f.fsys.Open(filepath.FromSlash(name))
+}
+
+func (f *fromFs) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	return f.fsys.ReadFile(filepath.FromSlash(name))
+}
+
+func (f *fromFs) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	infos, err := f.fsys.ReadDir(filepath.FromSlash(name))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fileInfoDirEntry{info}
+	}
+	// fs.ReadDirFS requires the entries to be sorted by name, which not
+	// every FS guarantees.
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// fileInfoDirEntry is an fs.DirEntry describing a file with the given info.
+type fileInfoDirEntry struct {
+	info os.FileInfo
+}
+
+func (e fileInfoDirEntry) Name() string               { return e.info.Name() }
+func (e fileInfoDirEntry) IsDir() bool                { return e.info.IsDir() }
+func (e fileInfoDirEntry) Type() fs.FileMode          { return e.info.Mode().Type() }
+func (e fileInfoDirEntry) Info() (fs.FileInfo, error) { return e.info, nil }
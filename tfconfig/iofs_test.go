@@ -0,0 +1,68 @@
+package tfconfig
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+var testMapFS = fstest.MapFS{
+	"modules/app/main.tf": &fstest.MapFile{Data: []byte(`
+variable "name" {
+  type        = string
+  description = "Name of the app"
+}
+
+output "name" {
+  value = var.name
+}
+`)},
+	"modules/app/README.md": &fstest.MapFile{Data: []byte("# app\n")},
+}
+
+func TestLoadModuleFromIOFS(t *testing.T) {
+	dir := filepath.Join("modules", "app")
+	module, diags := LoadModuleFromIOFS(testMapFS, dir)
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	v, ok := module.Variables["name"]
+	if !ok {
+		t.Fatalf("variable %q was not loaded", "name")
+	}
+	if got, want := v.Description, "Name of the app"; got != want {
+		t.Errorf("wrong description %q; want %q", got, want)
+	}
+	if got, want := v.Pos.Filename, filepath.Join(dir, "main.tf"); got != want {
+		t.Errorf("wrong filename %q; want %q", got, want)
+	}
+	if _, ok := module.Outputs["name"]; !ok {
+		t.Errorf("output %q was not loaded", "name")
+	}
+}
+
+func TestFromIOFSInvalidPath(t *testing.T) {
+	fsys := FromIOFS(testMapFS)
+	for _, name := range []string{"/modules/app/main.tf", filepath.Join("..", "main.tf")} {
+		if _, err := fsys.ReadFile(name); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("wrong error reading %q: %v; want %v", name, err, fs.ErrInvalid)
+		}
+	}
+}
+
+func TestToIOFS(t *testing.T) {
+	if err := fstest.TestFS(ToIOFS(FromIOFS(testMapFS)), "modules/app/main.tf", "modules/app/README.md"); err != nil {
+		t.Error(err)
+	}
+
+	fsys, err := fs.Sub(ToIOFS(NewOsFs()), "testdata/lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(fsys, "lint.tf"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,198 @@
+package tfconfig
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// JSONSchemaDialect is the JSON Schema dialect used by ModuleJSONSchema.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is the subset of JSON Schema needed to describe the input
+// variables of a module.
+//
+// Metadata that has no JSON Schema equivalent is included as "x-" vendor
+// extension keywords.
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+
+	Items       *JSONSchema   `json:"items,omitempty"`
+	PrefixItems []*JSONSchema `json:"prefixItems,omitempty"`
+	UniqueItems bool          `json:"uniqueItems,omitempty"`
+	MinItems    *int          `json:"minItems,omitempty"`
+	MaxItems    *int          `json:"maxItems,omitempty"`
+
+	Enum      []interface{} `json:"enum,omitempty"`
+	Default   interface{}   `json:"default,omitempty"`
+	Pattern   string        `json:"pattern,omitempty"`
+	Minimum   *float64      `json:"minimum,omitempty"`
+	Maximum   *float64      `json:"maximum,omitempty"`
+	MinLength *int          `json:"minLength,omitempty"`
+	MaxLength *int          `json:"maxLength,omitempty"`
+
+	WriteOnly  bool `json:"writeOnly,omitempty"`
+	Deprecated bool `json:"deprecated,omitempty"`
+
+	Hidden         *bool         `json:"x-hidden,omitempty"`
+	Immutable      *bool         `json:"x-immutable,omitempty"`
+	CloudDataType  string        `json:"x-cloud-data-type,omitempty"`
+	CloudDataRange []interface{} `json:"x-cloud-data-range,omitempty"`
+}
+
+// RenderJSONSchema writes a JSON Schema document describing the input
+// variables of the given module.
+func RenderJSONSchema(w io.Writer, module *Module) error {
+	j, err := json.MarshalIndent(ModuleJSONSchema(module), "", "  ")
+	if err != nil {
+		return err
+	}
+	j = append(j, '\n')
+	_, err = w.Write(j)
+	return err
+}
+
+// ModuleJSONSchema returns a JSON Schema describing an object whose
+// properties are the input variables of the given module, such as the
+// contents of a terraform.tfvars.json file.
+//
+// Each variable's type constraint is translated into the closest JSON Schema
+// equivalent, and any metadata added by LoadIBMModule is translated into
+// validation keywords.
+func ModuleJSONSchema(module *Module) *JSONSchema {
+	schema := &JSONSchema{
+		Schema:     JSONSchemaDialect,
+		Title:      module.Path,
+		Type:       "object",
+		Properties: make(map[string]*JSONSchema, len(module.Variables)),
+	}
+	for _, name := range SortedKeysOfMap(module.Variables) {
+		v := module.Variables[name]
+		schema.Properties[name] = variableJSONSchema(v)
+		if v.Required != nil && *v.Required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+func variableJSONSchema(v *Variable) *JSONSchema {
+	ty := variableType(v)
+	schema := typeJSONSchema(ty)
+
+	schema.Description = v.Description
+	schema.Default = v.Default
+	schema.Pattern = v.Matches
+	schema.Deprecated = v.Deprecated != ""
+	schema.WriteOnly = v.Sensitive != nil && *v.Sensitive
+	schema.Hidden = v.Hidden
+	schema.Immutable = v.Immutable
+	schema.CloudDataType = v.CloudDataType
+	schema.CloudDataRange = v.CloudDataRange
+
+	if v.AllowedValues != "" {
+		for _, opt := range strings.Split(v.AllowedValues, ",") {
+			schema.Enum = append(schema.Enum, jsonSchemaEnumValue(ty, strings.TrimSpace(opt)))
+		}
+	}
+	if f, err := strconv.ParseFloat(v.MinValue, 64); err == nil {
+		schema.Minimum = &f
+	}
+	if f, err := strconv.ParseFloat(v.MaxValue, 64); err == nil {
+		schema.Maximum = &f
+	}
+	schema.MinLength = intFromMetadata(v.MinValueLength)
+	schema.MaxLength = intFromMetadata(v.MaxValueLength)
+	schema.MinItems = v.MinItems
+	schema.MaxItems = v.MaxItems
+
+	return schema
+}
+
+// typeJSONSchema returns the JSON Schema equivalent of the given Terraform
+// type constraint. Dynamic types produce an unconstrained schema.
+func typeJSONSchema(ty cty.Type) *JSONSchema {
+	switch {
+	case ty == cty.String:
+		return &JSONSchema{Type: "string"}
+	case ty == cty.Number:
+		return &JSONSchema{Type: "number"}
+	case ty == cty.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case ty.IsListType():
+		return &JSONSchema{Type: "array", Items: typeJSONSchema(ty.ElementType())}
+	case ty.IsSetType():
+		return &JSONSchema{Type: "array", Items: typeJSONSchema(ty.ElementType()), UniqueItems: true}
+	case ty.IsTupleType():
+		schema := &JSONSchema{Type: "array"}
+		for _, ety := range ty.TupleElementTypes() {
+			schema.PrefixItems = append(schema.PrefixItems, typeJSONSchema(ety))
+		}
+		return schema
+	case ty.IsMapType():
+		return &JSONSchema{Type: "object", AdditionalProperties: typeJSONSchema(ty.ElementType())}
+	case ty.IsObjectType():
+		schema := &JSONSchema{Type: "object"}
+		atys := ty.AttributeTypes()
+		if len(atys) > 0 {
+			schema.Properties = make(map[string]*JSONSchema, len(atys))
+		}
+		for name, aty := range atys {
+			schema.Properties[name] = typeJSONSchema(aty)
+			schema.Required = append(schema.Required, name)
+		}
+		sort.Strings(schema.Required)
+		return schema
+	default:
+		return &JSONSchema{}
+	}
+}
+
+// jsonSchemaEnumValue converts one of the comma-separated allowed values
+// recorded in provider metadata into a value of the variable's type, falling
+// back on the original string if it doesn't conform.
+func jsonSchemaEnumValue(ty cty.Type, opt string) interface{} {
+	switch ty {
+	case cty.Number:
+		if f, err := strconv.ParseFloat(opt, 64); err == nil {
+			return f
+		}
+	case cty.Bool:
+		if b, err := strconv.ParseBool(opt); err == nil {
+			return b
+		}
+	}
+	return opt
+}
+
+// intFromMetadata interprets the loosely-typed numeric values that can
+// appear in provider metadata, returning nil if the value isn't a number.
+func intFromMetadata(v interface{}) *int {
+	var i int
+	switch n := v.(type) {
+	case int:
+		i = n
+	case float64:
+		i = int(n)
+	case string:
+		parsed, err := strconv.Atoi(n)
+		if err != nil {
+			return nil
+		}
+		i = parsed
+	default:
+		return nil
+	}
+	return &i
+}
@@ -0,0 +1,118 @@
+package tfconfig
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestModuleJSONSchema(t *testing.T) {
+	required := true
+	sensitive := true
+	hidden := true
+	minItems := 1
+	module := NewModule("example")
+	module.Variables["region"] = &Variable{
+		Name:          "region",
+		Type:          "string",
+		Description:   "The region to deploy into",
+		Required:      &required,
+		AllowedValues: "us-south, eu-de",
+		CloudDataType: "region",
+		Hidden:        &hidden,
+	}
+	module.Variables["count"] = &Variable{
+		Name:     "count",
+		Type:     "number",
+		Default:  float64(2),
+		MinValue: "1",
+		MaxValue: "10",
+	}
+	module.Variables["name"] = &Variable{
+		Name:           "name",
+		Default:        "example",
+		Matches:        "^[a-z]+$",
+		MinValueLength: float64(1),
+		MaxValueLength: 63,
+	}
+	module.Variables["tags"] = &Variable{
+		Name:     "tags",
+		Type:     "set(string)",
+		MinItems: &minItems,
+	}
+	module.Variables["api_key"] = &Variable{
+		Name:      "api_key",
+		Type:      "string",
+		Required:  &required,
+		Sensitive: &sensitive,
+	}
+	module.Variables["settings"] = &Variable{
+		Name: "settings",
+		Type: "object({ size = number, labels = map(string) })",
+	}
+
+	gotSrc, err := json.Marshal(ModuleJSONSchema(module))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(gotSrc, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"$schema":  JSONSchemaDialect,
+		"title":    "example",
+		"type":     "object",
+		"required": []interface{}{"api_key", "region"},
+		"properties": map[string]interface{}{
+			"api_key": map[string]interface{}{
+				"type":      "string",
+				"writeOnly": true,
+			},
+			"count": map[string]interface{}{
+				"type":    "number",
+				"default": float64(2),
+				"minimum": float64(1),
+				"maximum": float64(10),
+			},
+			"name": map[string]interface{}{
+				"default":   "example",
+				"pattern":   "^[a-z]+$",
+				"minLength": float64(1),
+				"maxLength": float64(63),
+			},
+			"region": map[string]interface{}{
+				"type":              "string",
+				"description":       "The region to deploy into",
+				"enum":              []interface{}{"us-south", "eu-de"},
+				"x-hidden":          true,
+				"x-cloud-data-type": "region",
+			},
+			"settings": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": map[string]interface{}{"type": "string"},
+					},
+					"size": map[string]interface{}{"type": "number"},
+				},
+				"required": []interface{}{"labels", "size"},
+			},
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"uniqueItems": true,
+				"minItems":    float64(1),
+			},
+		},
+	}
+
+	if diff := deep.Equal(got, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package tfconfig
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// JUnitSuite is the result of checking a single module directory, reported
+// by RenderJUnit as a test suite.
+type JUnitSuite struct {
+	// Dir is the module directory, which names the suite. Source file
+	// paths are reported relative to it.
+	Dir string
+
+	// Checks are the IDs of the checks that were run, such as rule IDs. Each
+	// check that found no problems is reported as a passing test case.
+	Checks []string
+
+	// Diagnostics are the problems found, each reported as a failing test
+	// case.
+	Diagnostics Diagnostics
+}
+
+// NewJUnitSuites returns a suite for each of the given module directories,
+// all of which ran the given checks, assigning each of the given
+// diagnostics to the suite of the directory containing its source file.
+// Diagnostics without a source position, or whose source file isn't
+// directly within one of the directories, are assigned to the first suite.
+func NewJUnitSuites(dirs []string, checks []string, diags Diagnostics) []*JUnitSuite {
+	suites := make([]*JUnitSuite, len(dirs))
+	byDir := make(map[string]*JUnitSuite, len(dirs))
+	for i, dir := range dirs {
+		suites[i] = &JUnitSuite{Dir: dir, Checks: checks}
+		byDir[filepath.Clean(dir)] = suites[i]
+	}
+	if len(suites) == 0 {
+		return suites
+	}
+	for _, diag := range diags {
+		suite := suites[0]
+		if diag.Pos != nil {
+			if s, ok := byDir[filepath.Dir(diag.Pos.Filename)]; ok {
+				suite = s
+			}
+		}
+		suite.Diagnostics = append(suite.Diagnostics, diag)
+	}
+	return suites
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// RenderJUnit writes the given suites as a JUnit XML report.
+//
+// Each problem is reported as a failing test case whose class name is the
+// ID of the rule that found it, or SARIFDefaultRuleID, and whose name is
+// its source position. Each check that found no problems in a suite is
+// reported as a passing test case named after the check.
+func RenderJUnit(w io.Writer, suites []*JUnitSuite) error {
+	report := &junitTestSuites{}
+	for _, suite := range suites {
+		ts := &junitTestSuite{Name: filepath.ToSlash(suite.Dir)}
+
+		failed := make(map[string]bool)
+		for _, diag := range suite.Diagnostics {
+			ruleID := diag.Rule
+			if ruleID == "" {
+				ruleID = SARIFDefaultRuleID
+			}
+			failed[ruleID] = true
+
+			name := ruleID
+			text := diagnosticMessage(diag)
+			if diag.Pos != nil && diag.Pos.Filename != "" {
+				name = fmt.Sprintf("%s:%d", relativeSlashPath(suite.Dir, diag.Pos.Filename), diag.Pos.Line)
+				text = fmt.Sprintf("%s: %s", name, text)
+			}
+			severity := "warning"
+			if diag.Severity == DiagError {
+				severity = "error"
+			}
+			ts.Cases = append(ts.Cases, &junitTestCase{
+				ClassName: ruleID,
+				Name:      name,
+				Failure: &junitFailure{
+					Message: diagnosticMessage(diag),
+					Type:    severity,
+					Text:    text,
+				},
+			})
+			ts.Failures++
+		}
+
+		for _, check := range suite.Checks {
+			if !failed[check] {
+				ts.Cases = append(ts.Cases, &junitTestCase{
+					ClassName: check,
+					Name:      check,
+				})
+			}
+		}
+
+		ts.Tests = len(ts.Cases)
+		report.Tests += ts.Tests
+		report.Failures += ts.Failures
+		report.Suites = append(report.Suites, ts)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,69 @@
+package tfconfig
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestRenderJUnit(t *testing.T) {
+	path := filepath.Join("testdata", "lint")
+	module, diags := LoadModule(path)
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+	diags = append(diags, Lint(module, LintRules)...)
+
+	var checks []string
+	for _, rule := range LintRules {
+		checks = append(checks, rule.ID())
+	}
+	checks = append(checks, SARIFDefaultRuleID)
+
+	expected, err := ioutil.ReadFile(filepath.Join(path, "lint.out.junit.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderJUnit(&buf, NewJUnitSuites([]string{path}, checks, diags)); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(buf.String(), string(expected)); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
+
+func TestNewJUnitSuites(t *testing.T) {
+	root := filepath.Join("testdata", "module-tree")
+	child := filepath.Join(root, "network")
+	diags := Diagnostics{
+		{Summary: "root", Pos: &SourcePos{Filename: filepath.Join(root, "main.tf"), Line: 1}},
+		{Summary: "child", Pos: &SourcePos{Filename: filepath.Join(child, "main.tf"), Line: 1}},
+		{Summary: "nowhere"},
+	}
+
+	suites := NewJUnitSuites([]string{root, child}, []string{"config"}, diags)
+
+	got := make(map[string][]string)
+	for _, suite := range suites {
+		for _, diag := range suite.Diagnostics {
+			got[suite.Dir] = append(got[suite.Dir], diag.Summary)
+		}
+	}
+	want := map[string][]string{
+		root:  {"root", "nowhere"},
+		child: {"child"},
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package tfconfig
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LintRule is a Rule implemented by a Go function, as used by the built-in
+// rules.
+type LintRule struct {
+	// Name identifies the rule, for enabling or disabling it.
+	Name string
+
+	Description string
+
+	// Check returns a diagnostic for each problem the rule finds in the
+	// given module.
+	Check func(module *Module) Diagnostics
+}
+
+// ID implements Rule.
+func (r *LintRule) ID() string {
+	return r.Name
+}
+
+// CheckModule implements Rule.
+func (r *LintRule) CheckModule(module *Module) Diagnostics {
+	return r.Check(module)
+}
+
+// LintRules are the built-in rules, in the order that Lint runs them.
+var LintRules = []Rule{
+	&LintRule{
+		Name:        "variable_description",
+		Description: "Variables should have a description.",
+		Check:       lintVariableDescription,
+	},
+	&LintRule{
+		Name:        "variable_type",
+		Description: "Variables should have a type constraint.",
+		Check:       lintVariableType,
+	},
+	&LintRule{
+		Name:        "unused_variable",
+		Description: "Variables should be referred to by a resource, module call, local value, provider configuration or output.",
+		Check:       lintUnusedVariable,
+	},
+	&LintRule{
+		Name:        "output_description",
+		Description: "Outputs should have a description.",
+		Check:       lintOutputDescription,
+	},
+	&LintRule{
+		Name:        "sensitive_output",
+		Description: "Outputs whose values derive from sensitive variables must be marked as sensitive.",
+		Check:       lintSensitiveOutput,
+	},
+	&LintRule{
+		Name:        "provider_version",
+		Description: "Required providers should have a version constraint.",
+		Check:       lintProviderVersion,
+	},
+	&LintRule{
+		Name:        "module_version",
+		Description: "Calls to modules from a registry should have a version constraint.",
+		Check:       lintModuleVersion,
+	},
+}
+
+// SelectLintRules returns the built-in rules selected as by SelectRules.
+func SelectLintRules(enable, disable []string) ([]Rule, error) {
+	return SelectRules(LintRules, enable, disable)
+}
+
+func lintVariableDescription(module *Module) Diagnostics {
+	var diags Diagnostics
+	for _, name := range SortedKeysOfMap(module.Variables) {
+		v := module.Variables[name]
+		if v.Description == "" {
+			diags = append(diags, Diagnostic{
+				Severity: DiagWarning,
+				Summary:  "Missing variable description",
+				Detail:   fmt.Sprintf("Variable %q has no description.", name),
+				Pos:      v.Pos,
+			})
+		}
+	}
+	return diags
+}
+
+func lintVariableType(module *Module) Diagnostics {
+	var diags Diagnostics
+	for _, name := range SortedKeysOfMap(module.Variables) {
+		v := module.Variables[name]
+		if v.Type == "" {
+			diags = append(diags, Diagnostic{
+				Severity: DiagWarning,
+				Summary:  "Missing variable type",
+				Detail:   fmt.Sprintf("Variable %q has no type constraint, so any value is accepted.", name),
+				Pos:      v.Pos,
+			})
+		}
+	}
+	return diags
+}
+
+func lintUnusedVariable(module *Module) Diagnostics {
+	used := make(map[string]bool)
+	for _, ref := range moduleReferences(module) {
+		used[ref] = true
+	}
+
+	var diags Diagnostics
+	for _, name := range SortedKeysOfMap(module.Variables) {
+		if !used["var."+name] {
+			diags = append(diags, Diagnostic{
+				Severity: DiagWarning,
+				Summary:  "Unused variable",
+				Detail:   fmt.Sprintf("Variable %q is declared but never used.", name),
+				Pos:      module.Variables[name].Pos,
+			})
+		}
+	}
+	return diags
+}
+
+// moduleReferences returns the references made by every object in the
+// given module.
+func moduleReferences(module *Module) []string {
+	var refs []string
+	for _, l := range module.Locals {
+		refs = append(refs, l.References...)
+	}
+	for _, resources := range []map[string]*Resource{module.ManagedResources, module.DataResources} {
+		for _, r := range resources {
+			refs = append(refs, r.References...)
+		}
+	}
+	for _, mc := range module.ModuleCalls {
+		refs = append(refs, mc.References...)
+	}
+	for _, pc := range module.ProviderConfigs {
+		refs = append(refs, pc.References...)
+	}
+	for _, o := range module.Outputs {
+		refs = append(refs, o.References...)
+	}
+	return refs
+}
+
+func lintOutputDescription(module *Module) Diagnostics {
+	var diags Diagnostics
+	for _, name := range SortedKeysOfMap(module.Outputs) {
+		o := module.Outputs[name]
+		if o.Description == "" {
+			diags = append(diags, Diagnostic{
+				Severity: DiagWarning,
+				Summary:  "Missing output description",
+				Detail:   fmt.Sprintf("Output %q has no description.", name),
+				Pos:      o.Pos,
+			})
+		}
+	}
+	return diags
+}
+
+func lintSensitiveOutput(module *Module) Diagnostics {
+	var diags Diagnostics
+	for _, name := range SortedKeysOfMap(module.Outputs) {
+		o := module.Outputs[name]
+		if o.Sensitive {
+			continue
+		}
+		vars := sensitiveVariablesReferenced(module, o.References, make(map[string]bool))
+		if len(vars) == 0 {
+			continue
+		}
+		diags = append(diags, Diagnostic{
+			Severity: DiagError,
+			Summary:  "Output refers to sensitive values",
+			Detail:   fmt.Sprintf("The value of output %q derives from the sensitive %s, so the output must also be marked as sensitive.", name, strings.Join(vars, ", ")),
+			Pos:      o.Pos,
+		})
+	}
+	return diags
+}
+
+// sensitiveVariablesReferenced returns the sorted addresses of the sensitive
+// variables that the given references refer to, either directly or via
+// local values.
+func sensitiveVariablesReferenced(module *Module, refs []string, seen map[string]bool) []string {
+	var ret []string
+	for _, ref := range refs {
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		switch {
+		case strings.HasPrefix(ref, "var."):
+			if v, ok := module.Variables[strings.TrimPrefix(ref, "var.")]; ok && isTrue(v.Sensitive) {
+				ret = append(ret, ref)
+			}
+		case strings.HasPrefix(ref, "local."):
+			if l, ok := module.Locals[strings.TrimPrefix(ref, "local.")]; ok {
+				ret = append(ret, sensitiveVariablesReferenced(module, l.References, seen)...)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func lintProviderVersion(module *Module) Diagnostics {
+	var diags Diagnostics
+	for _, name := range SortedKeysOfMap(module.RequiredProviders) {
+		if len(module.RequiredProviders[name].VersionConstraints) > 0 {
+			continue
+		}
+		diags = append(diags, Diagnostic{
+			Severity: DiagWarning,
+			Summary:  "Missing provider version constraint",
+			Detail:   fmt.Sprintf("Provider %q has no version constraint, so any version may be installed. Add one to the required_providers block.", name),
+			Pos:      providerUsePos(module, name),
+		})
+	}
+	return diags
+}
+
+// providerUsePos returns the position of the first resource that uses the
+// given provider, or nil if there is none.
+func providerUsePos(module *Module, name string) *SourcePos {
+	var pos *SourcePos
+	for _, resources := range []map[string]*Resource{module.ManagedResources, module.DataResources} {
+		for _, r := range resources {
+			if r.Provider.Name == name && (pos == nil || sourcePosLess(&r.Pos, pos)) {
+				p := r.Pos
+				pos = &p
+			}
+		}
+	}
+	return pos
+}
+
+func lintModuleVersion(module *Module) Diagnostics {
+	var diags Diagnostics
+	for _, name := range SortedKeysOfMap(module.ModuleCalls) {
+		mc := module.ModuleCalls[name]
+		if mc.Version != "" || !isRegistryModuleSource(mc.Source) {
+			continue
+		}
+		pos := mc.Pos
+		diags = append(diags, Diagnostic{
+			Severity: DiagWarning,
+			Summary:  "Missing module version constraint",
+			Detail:   fmt.Sprintf("Module call %q uses %q from a registry without a version constraint, so any version may be installed.", name, mc.Source),
+			Pos:      &pos,
+		})
+	}
+	return diags
+}
+
+// registryModuleSourceRe matches module registry addresses such as
+// "terraform-ibm-modules/vpc/ibm" or
+// "registry.example.com/namespace/name/provider//modules/sub".
+var registryModuleSourceRe = regexp.MustCompile(`^([0-9A-Za-z.:-]+/)?[0-9A-Za-z_-]+/[0-9A-Za-z_-]+/[0-9a-z]+(//.*)?$`)
+
+func isRegistryModuleSource(source string) bool {
+	if isLocalModuleSource(source) || strings.Contains(source, "::") {
+		return false
+	}
+	for _, prefix := range []string{"github.com/", "bitbucket.org/"} {
+		if strings.HasPrefix(source, prefix) {
+			// Shorthands for Git repositories
+			return false
+		}
+	}
+	return registryModuleSourceRe.MatchString(source)
+}
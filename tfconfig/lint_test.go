@@ -0,0 +1,202 @@
+package tfconfig
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-test/deep"
+)
+
+func TestLint(t *testing.T) {
+	path := filepath.Join("testdata", "lint")
+	module, _ := LoadModule(path)
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+	filename := filepath.Join(path, "lint.tf")
+
+	tests := map[string][]Diagnostic{
+		"variable_description": {
+			{
+				Severity: DiagWarning,
+				Summary:  "Missing variable description",
+				Detail:   `Variable "name" has no description.`,
+				Pos:      &SourcePos{Filename: filename, Line: 19},
+			},
+		},
+		"variable_type": {
+			{
+				Severity: DiagWarning,
+				Summary:  "Missing variable type",
+				Detail:   `Variable "unused" has no type constraint, so any value is accepted.`,
+				Pos:      &SourcePos{Filename: filename, Line: 23},
+			},
+		},
+		"unused_variable": {
+			{
+				Severity: DiagWarning,
+				Summary:  "Unused variable",
+				Detail:   `Variable "unused" is declared but never used.`,
+				Pos:      &SourcePos{Filename: filename, Line: 23},
+			},
+		},
+		"output_description": {
+			{
+				Severity: DiagWarning,
+				Summary:  "Missing output description",
+				Detail:   `Output "suffix" has no description.`,
+				Pos:      &SourcePos{Filename: filename, Line: 72},
+			},
+		},
+		"sensitive_output": {
+			{
+				Severity: DiagError,
+				Summary:  "Output refers to sensitive values",
+				Detail:   `The value of output "credentials" derives from the sensitive var.api_key, so the output must also be marked as sensitive.`,
+				Pos:      &SourcePos{Filename: filename, Line: 67},
+			},
+		},
+		"provider_version": {
+			{
+				Severity: DiagWarning,
+				Summary:  "Missing provider version constraint",
+				Detail:   `Provider "random" has no version constraint, so any version may be installed. Add one to the required_providers block.`,
+				Pos:      &SourcePos{Filename: filename, Line: 41},
+			},
+		},
+		"module_version": {
+			{
+				Severity: DiagWarning,
+				Summary:  "Missing module version constraint",
+				Detail:   `Module call "registry" uses "terraform-ibm-modules/vpc/ibm" from a registry without a version constraint, so any version may be installed.`,
+				Pos:      &SourcePos{Filename: filename, Line: 45},
+			},
+		},
+	}
+
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			rules, err := SelectLintRules([]string{name}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Lint(module, rules)
+			for i := range want {
+				want[i].Rule = name
+				want[i].Code = name
+			}
+			if diff := deep.Equal(got, Diagnostics(want)); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectLintRules(t *testing.T) {
+	rules, err := SelectLintRules(nil, []string{"unused_variable", "variable_type"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rule := range rules {
+		got = append(got, rule.ID())
+	}
+	want := []string{
+		"variable_description", "output_description", "sensitive_output",
+		"provider_version", "module_version",
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+
+	if _, err := SelectLintRules([]string{"no_such_rule"}, nil); err == nil {
+		t.Errorf("no error for unknown rule")
+	}
+}
+
+func TestIsRegistryModuleSource(t *testing.T) {
+	tests := map[string]bool{
+		"terraform-ibm-modules/vpc/ibm":                 true,
+		"terraform-ibm-modules/vpc/ibm//modules/subnet": true,
+		"registry.example.com/namespace/name/provider":  true,
+		"./modules/vpc":                                      false,
+		"../vpc":                                             false,
+		"github.com/example/module":                          false,
+		"git::https://example.com/vpc.git":                   false,
+		"https://example.com/vpc-module.zip":                 false,
+		"s3::https://s3.amazonaws.com/bucket/vpc-module.zip": false,
+	}
+	for source, want := range tests {
+		if got := isRegistryModuleSource(source); got != want {
+			t.Errorf("isRegistryModuleSource(%q) = %t; want %t", source, got, want)
+		}
+	}
+}
+
+func TestLintLegacySyntax(t *testing.T) {
+	module := loadLegacyTestModule(t)
+	rules, err := SelectRules(LintRules, []string{"unused_variable"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := Lint(module, rules)
+	var got []string
+	for _, diag := range diags {
+		got = append(got, diag.Detail)
+	}
+	want := []string{`Variable "unused" is declared but never used.`}
+	if diff := deep.Equal(got, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
+
+func TestLintLifecycleConditions(t *testing.T) {
+	src := `
+variable "min_size" {}
+variable "max_size" {}
+variable "unused" {}
+
+resource "ibm_is_instance" "vm" {
+  name = "vm"
+
+  lifecycle {
+    ignore_changes = [tags]
+
+    precondition {
+      condition     = var.min_size > 0
+      error_message = "The minimum size must be positive."
+    }
+
+    postcondition {
+      condition     = self.size <= var.max_size
+      error_message = "The instance is too large."
+    }
+  }
+}
+`
+	module, diags := LoadModuleFromIOFS(fstest.MapFS{"main.tf": {Data: []byte(src)}}, ".")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	rules, err := SelectRules(LintRules, []string{"unused_variable"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, diag := range Lint(module, rules) {
+		got = append(got, diag.Detail)
+	}
+	want := []string{`Variable "unused" is declared but never used.`}
+	if diff := deep.Equal(got, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
@@ -1,9 +1,8 @@
 package tfconfig
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"path/filepath"
 	"reflect"
@@ -19,24 +18,52 @@ func LoadModule(dir string) (*Module, Diagnostics) {
 	return LoadModuleFromFilesystem(NewOsFs(), dir)
 }
 
-// LoadIBMModule takes template file directory and metadataPath as input and returns final module struct.
+// CheckForInitDirectoryAndLoadIBMModule checks that the module in the given
+// directory has been initialized with "terraform init", so that the modules
+// it calls have been installed, and then loads it with LoadIBMModule.
+//
+// It returns a nil module only if the directory has not been initialized.
+// Otherwise the returned diagnostics are also recorded in the Diagnostics
+// of the returned module.
 func CheckForInitDirectoryAndLoadIBMModule(dir string, metadataPath string) (*Module, Diagnostics) {
-	var err Diagnostics
+	metadata, diags := loadMetadataPath(metadataPath)
+	module, moreDiags := CheckForInitDirectoryAndLoadIBMModuleFromFilesystem(NewOsFs(), dir, metadata)
+	diags = append(diags, moreDiags...)
+	if module != nil {
+		module.Diagnostics = diags
+	}
+	return module, diags
+}
+
+// CheckForInitDirectoryAndLoadIBMModuleFromFilesystem is a variant of
+// CheckForInitDirectoryAndLoadIBMModule that reads from the given FS and
+// takes already parsed metadata, which may be nil.
+func CheckForInitDirectoryAndLoadIBMModuleFromFilesystem(fs FS, dir string, metadata ProviderMetadata) (*Module, Diagnostics) {
+	return checkForInitDirectoryAndLoadIBMModule(fs, dir, metadata, nil)
+}
+
+// checkForInitDirectoryAndLoadIBMModule implements
+// CheckForInitDirectoryAndLoadIBMModuleFromFilesystem, using the given
+// cache if it is not nil.
+func checkForInitDirectoryAndLoadIBMModule(fs FS, dir string, metadata ProviderMetadata, cache *Cache) (*Module, Diagnostics) {
+	var diags Diagnostics
 	fileStruct := make(map[string]interface{})
 	// Check for init directory ./terraform and return error if it is not present
-	_, initDirErr := ioutil.ReadDir(dir + "/.terraform/")
+	initDir := filepath.Join(dir, ".terraform")
+	_, initDirErr := fs.ReadDir(initDir)
 	if initDirErr != nil {
-		err = append(err, Diagnostic{
+		diags = append(diags, Diagnostic{
 			Severity: DiagError,
-			Summary:  "initDirErr",
-			Detail:   fmt.Sprintf("Failed to read init module directory of %s. Please run terraform init if it is not run earlier to load the modules: %s", dir+"/.terraform/", initDirErr),
+			Summary:  "Module not initialized",
+			Code:     DiagCodeNotInitialized,
+			Detail:   fmt.Sprintf("Failed to read init module directory of %s. Please run terraform init if it is not run earlier to load the modules: %s", initDir, initDirErr),
 		})
-		return nil, err
+		return nil, diags
 	}
 
 	// Check for modules directory ./terraform/modules and will not return error assuming that no modules are present.
 	// Store directories inside the modules folders as file struct.
-	moduleFilesInfo, moduleDirErr := ioutil.ReadDir(dir + "/.terraform/modules/")
+	moduleFilesInfo, moduleDirErr := fs.ReadDir(filepath.Join(dir, moduleInstallDir))
 	if moduleDirErr == nil {
 		for _, f := range moduleFilesInfo {
 			if f.IsDir() {
@@ -47,49 +74,44 @@ func CheckForInitDirectoryAndLoadIBMModule(dir string, metadataPath string) (*Mo
 		log.Printf("[INFO] This template doesn't have any modules and hence no modules are downloaded for %s", dir)
 	}
 	// LoadIBMModule to extract metadata
-	loadedModule, loadedModuleErr := LoadIBMModule(dir, metadataPath, fileStruct)
-	if loadedModuleErr != nil {
-		err = append(err, Diagnostic{
-			Severity: DiagError,
-			Summary:  "loadedModuleErr",
-			Detail:   fmt.Sprintf("Failed to LoadIBMModule for %s", loadedModuleErr),
-		})
-		return nil, err
+	if cache != nil {
+		return cache.LoadIBMModule(fs, dir, metadata, fileStruct)
 	}
-	return loadedModule, nil
+	return LoadIBMModuleFromFilesystem(fs, dir, metadata, fileStruct)
 }
 
 // LoadIBMModule takes template file directory and metadataPath as input and returns final module struct.
+//
+// The returned diagnostics, which are also recorded in the Diagnostics of
+// the returned module, include those of each module it calls, with their
+// Module set to the address of the module they came from. Warnings don't
+// stop the enrichment of the module with metadata.
 func LoadIBMModule(dir string, metadataPath string, fileStruct map[string]interface{}) (*Module, Diagnostics) {
-	var metadata map[string]interface{}
-	var err Diagnostics
-	loadModule, LoadModuleFromFilesystemErr := LoadModuleFromFilesystem(NewOsFs(), dir)
-	if LoadModuleFromFilesystemErr != nil {
-		err = append(err, Diagnostic{
-			Severity: DiagError,
-			Summary:  "LoadModuleFromFilesystemErr",
-			Detail:   fmt.Sprintf("%s", LoadModuleFromFilesystemErr),
-		})
-	}
-	if metadataPath != "" {
-		metadataBytes, metadataErr := ioutil.ReadFile(metadataPath)
-		if metadataErr != nil {
-			err = append(err, Diagnostic{
-				Severity: DiagError,
-				Summary:  "metadataErr",
-				Detail:   fmt.Sprintf("Failed to read metadataPath file %s %s", metadataPath, metadataErr),
-			})
-		}
+	metadata, diags := loadMetadataPath(metadataPath)
+	module, moreDiags := LoadIBMModuleFromFilesystem(NewOsFs(), dir, metadata, fileStruct)
+	diags = append(diags, moreDiags...)
+	module.Diagnostics = diags
+	return module, diags
+}
 
-		unmarshalErr := json.Unmarshal([]byte(metadataBytes), &metadata)
-		if unmarshalErr != nil {
-			err = append(err, Diagnostic{
-				Severity: DiagError,
-				Summary:  "unmarshalErr",
-				Detail:   fmt.Sprintf("Failed to unmarshal metadata json %s: %s", metadataPath, unmarshalErr),
-			})
-		}
+// LoadIBMModuleFromFilesystem is a variant of LoadIBMModule that reads from
+// the given FS and takes already parsed metadata, which may be nil.
+func LoadIBMModuleFromFilesystem(fs FS, dir string, metadata ProviderMetadata, fileStruct map[string]interface{}) (*Module, Diagnostics) {
+	l := newLoader(context.Background(), fs, &LoadOptions{Parallelism: 1})
+	return loadIBMModule(l, dir, metadata, fileStruct, "")
+}
+
+// loadMetadataPath loads the metadata file at the given path, if any.
+func loadMetadataPath(metadataPath string) (ProviderMetadata, Diagnostics) {
+	if metadataPath == "" {
+		return nil, nil
 	}
+	return LoadProviderMetadataFile(NewOsFs(), metadataPath)
+}
+
+func loadIBMModule(l *loader, dir string, metadata ProviderMetadata, fileStruct map[string]interface{}, address string) (*Module, Diagnostics) {
+	loadModule, diags := l.loadModule(dir)
+	diags = moduleDiagnostics(address, diags)
 	// Once the template is loaded and the Module is extracted, find metadata for variables using Module struct and above metadata file.
 	if loadModule.DataResources != nil {
 		findVariableMetadataFromResourceOrDatasource("data", loadModule.DataResources, loadModule.Variables, metadata)
@@ -98,19 +120,27 @@ func LoadIBMModule(dir string, metadataPath string, fileStruct map[string]interf
 		findVariableMetadataFromResourceOrDatasource("resource", loadModule.ManagedResources, loadModule.Variables, metadata)
 	}
 	if loadModule.ModuleCalls != nil && len(loadModule.ModuleCalls) != 0 {
-		loadModuleErr := findVariableMetadataFromModule(dir, metadataPath, fileStruct, loadModule.ModuleCalls, loadModule.Variables, metadata)
-		if loadModuleErr != nil {
-			err = append(err, Diagnostic{
-				Severity: DiagError,
-				Summary:  "loadModuleErr",
-				Detail:   fmt.Sprintf("Failed to load modules %s", loadModuleErr),
-			})
-		}
+		diags = append(diags, findVariableMetadataFromModule(l, dir, fileStruct, loadModule.ModuleCalls, loadModule.Variables, metadata, address)...)
 	}
 	if loadModule.Outputs != nil {
 		findOutputMetadataFromResourceOrDatasource(loadModule.Outputs, loadModule.Variables, loadModule.ModuleCalls, metadata)
 	}
-	return loadModule, err
+	loadModule.Diagnostics = diags
+	return loadModule, diags
+}
+
+// moduleDiagnostics records the given module address in each of the given
+// diagnostics that doesn't already have one.
+func moduleDiagnostics(address string, diags Diagnostics) Diagnostics {
+	if address == "" {
+		return diags
+	}
+	for i := range diags {
+		if diags[i].Module == "" {
+			diags[i].Module = address
+		}
+	}
+	return diags
 }
 
 // SortedKeysOfMap
@@ -176,16 +206,43 @@ func findSubModuleSourcePath(source string) string {
 	return ""
 }
 
+// moduleInstallDir is the directory, relative to a root module, into which
+// "terraform init" installs the modules it calls from non-local sources.
+var moduleInstallDir = filepath.Join(".terraform", "modules")
+
+// splitModuleInstallDir splits the given path of a module installed by
+// "terraform init" into the directory of the root module and the path of
+// the module within moduleInstallDir. It returns false if the path is not
+// within a moduleInstallDir.
+func splitModuleInstallDir(dir string) (rootDir, name string, ok bool) {
+	dir = filepath.Clean(dir)
+	sep := string(filepath.Separator)
+	if strings.HasPrefix(dir, moduleInstallDir+sep) {
+		return ".", dir[len(moduleInstallDir+sep):], true
+	}
+	i := strings.Index(dir, sep+moduleInstallDir+sep)
+	if i < 0 {
+		return "", "", false
+	}
+	rootDir = dir[:i]
+	if rootDir == "" {
+		rootDir = sep
+	}
+	return rootDir, dir[i+len(sep+moduleInstallDir+sep):], true
+}
+
 //findVariableMetadataFromModule:
-// dir -->template file directory and metadataPath
+// fs --> filesystem to read modules from, dir -->template file directory
 // modules --> modules details from Module struct, variables --> variables from module struct and metadata json as inputs
+// address --> address of the module making the calls, empty for the root module, which is recorded in the diagnostics of the called modules
 // This function first checks for downloaded modules of terraform init under /.terraform/modules/  directory
 // If the module name from modules struct matches any of the downloaded module, repeat the extraction LoadIBMModule
-func findVariableMetadataFromModule(dir, metadataPath string, fileStruct map[string]interface{}, modules map[string]*ModuleCall, variables map[string]*Variable, metadata map[string]interface{}) Diagnostics {
-	var err Diagnostics
+func findVariableMetadataFromModule(l *loader, dir string, fileStruct map[string]interface{}, modules map[string]*ModuleCall, variables map[string]*Variable, metadata ProviderMetadata, address string) Diagnostics {
+	var diags Diagnostics
 	parentModuleName := ""
-	if strings.Contains(dir, "/.terraform/modules/") && len(strings.Split(dir, "/.terraform/modules/")) > 1 {
-		parentModuleName = fmt.Sprintf("%s.", strings.Split(dir, "/.terraform/modules/")[1])
+	rootDir, installedName, installed := splitModuleInstallDir(dir)
+	if installed {
+		parentModuleName = fmt.Sprintf("%s.", filepath.ToSlash(installedName))
 	}
 	// since range on map picks keys in random order,
 	// we sort keys using SortedKeysOfMap and range on the the keys
@@ -194,38 +251,42 @@ func findVariableMetadataFromModule(dir, metadataPath string, fileStruct map[str
 		if module.Attributes != nil {
 			var modulePath string
 			if strings.HasPrefix(module.Source, "/") || strings.HasPrefix(module.Source, "./") || strings.HasPrefix(module.Source, "../") {
-				modulePath = dir + "/" + module.Source
+				modulePath = filepath.Join(dir, filepath.FromSlash(module.Source))
 			} else if _, ok := fileStruct[parentModuleName+module.Name]; ok {
 				// removing parent folder for child modules as child modules download under parent.child folder
-				if strings.Contains(dir, "/.terraform/modules/") {
-					rootDir := strings.Split(dir, "/.terraform/modules/")[0]
-					modulePath = rootDir + "/.terraform/modules/" + parentModuleName + module.Name
+				if installed {
+					modulePath = filepath.Join(rootDir, moduleInstallDir, parentModuleName+module.Name)
 				} else {
-					modulePath = dir + "/.terraform/modules/" + parentModuleName + module.Name
+					modulePath = filepath.Join(dir, moduleInstallDir, parentModuleName+module.Name)
 				}
 
 				subModuleDirectorySource := findSubModuleSourcePath(module.Source)
 				if subModuleDirectorySource != "" {
-					modulePath = modulePath + "/" + subModuleDirectorySource
+					modulePath = filepath.Join(modulePath, filepath.FromSlash(subModuleDirectorySource))
 				}
 			} else {
-				err = append(err, Diagnostic{
+				pos := module.Pos
+				diags = append(diags, Diagnostic{
 					Severity: DiagError,
-					Summary:  "module path error",
+					Summary:  "Unsupported module source",
+					Code:     DiagCodeModuleSource,
 					Detail:   fmt.Sprintf("module source %s is either incorrect or not supported by this tool", module.Source),
+					Pos:      &pos,
+					Module:   address,
 				})
-				return err
+				return diags
 			}
 			log.Printf("[INFO] Loading module '%s' from the path '%s'", module.Name, modulePath)
-			// Load inner module
-			loadedModulePath, LoadIBMModuleErr := LoadIBMModule(modulePath, metadataPath, fileStruct)
-			if LoadIBMModuleErr != nil {
-				err = append(err, Diagnostic{
-					Severity: DiagError,
-					Summary:  "LoadIBMModuleErr",
-					Detail:   fmt.Sprintf("Error while loading child modules %s", LoadIBMModuleErr),
-				})
-				return err
+			// Load inner module, keeping its diagnostics, which are
+			// annotated with its address. Only errors stop the enrichment.
+			childAddress := "module." + module.Name
+			if address != "" {
+				childAddress = address + "." + childAddress
+			}
+			loadedModulePath, childDiags := loadIBMModule(l, modulePath, metadata, fileStruct, childAddress)
+			diags = append(diags, childDiags...)
+			if childDiags.HasErrors() {
+				return diags
 			}
 
 			if loadedModulePath.ManagedResources != nil {
@@ -328,7 +389,7 @@ func findVariableMetadataFromModule(dir, metadataPath string, fileStruct map[str
 			}
 		}
 	}
-	return err
+	return diags
 }
 
 // findVariableMetadataFromResourceOrDatasource: This function is common for both resource and datasource.
@@ -610,25 +671,7 @@ func ExtractVariableMetadata(v *Variable, m interface{}, moduleName, moduleAttri
 // LoadModuleFromFilesystem reads the directory at the given path
 // in the given FS and attempts to interpret it as a Terraform module
 func LoadModuleFromFilesystem(fs FS, dir string) (*Module, Diagnostics) {
-	// For broad compatibility here we actually have two separate loader
-	// codepaths. The main one uses the new HCL parser and API and is intended
-	// for configurations from Terraform 0.12 onwards (though will work for
-	// many older configurations too), but we'll also fall back on one that
-	// uses the _old_ HCL implementation so we can deal with some edge-cases
-	// that are not valid in new HCL.
-
-	module, diags := loadModule(fs, dir)
-	if diags.HasErrors() {
-		// Try using the legacy HCL parser and see if we fare better.
-		legacyModule, legacyDiags := loadModuleLegacyHCL(fs, dir)
-		if !legacyDiags.HasErrors() {
-			legacyModule.init(legacyDiags)
-			return legacyModule, legacyDiags
-		}
-	}
-
-	module.init(diags)
-	return module, diags
+	return newLoader(context.Background(), fs, &LoadOptions{Parallelism: 1}).loadModule(dir)
 }
 
 // IsModuleDir checks if the given path contains terraform configuration files.
@@ -678,6 +721,7 @@ func dirFiles(fs FS, dir string) (primary []string, diags hcl.Diagnostics) {
 			Severity: hcl.DiagError,
 			Summary:  "Failed to read module directory",
 			Detail:   fmt.Sprintf("Module directory %s does not exist or cannot be read.", dir),
+			Extra:    diagCodeExtra(DiagCodeReadDir),
 		})
 		return
 	}
@@ -0,0 +1,172 @@
+package tfconfig
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// LoadOptions controls how the Context variants of the loading functions,
+// such as LoadModuleContext, read modules.
+type LoadOptions struct {
+	// Parallelism is the largest number of files that are read and parsed
+	// at once, across all of the modules being loaded. If it is zero or
+	// negative, runtime.GOMAXPROCS(0) is used. Unless it is 1, the FS must
+	// be safe for concurrent use, as those returned by this package are.
+	Parallelism int
+
+	// Cache, if not nil, is used to reuse the modules loaded before from
+	// the same files.
+	Cache *Cache
+}
+
+// LoadModuleContext is a variant of LoadModuleFromFilesystem that reads and
+// parses the module's files concurrently, as limited by the given options,
+// which may be nil. The result is the same as loading the files one by one,
+// however they are scheduled.
+//
+// If the given context is canceled or its deadline passes before the module
+// has been loaded, the files not yet read are skipped and the returned
+// diagnostics include an error with the code DiagCodeCanceled.
+func LoadModuleContext(ctx context.Context, fs FS, dir string, opts *LoadOptions) (*Module, Diagnostics) {
+	l := newLoader(ctx, fs, opts)
+	module, diags := l.loadModule(dir)
+	if canceled := l.canceled(); canceled != nil {
+		diags = append(diags, canceled...)
+		module.Diagnostics = diags
+	}
+	return module, diags
+}
+
+// loader loads modules from an FS, reading and parsing files in parallel up
+// to the limit given by LoadOptions. The non-Context loading functions use
+// a loader with a background context and a limit of 1.
+type loader struct {
+	ctx   context.Context
+	fs    FS
+	limit limiter
+	cache *Cache
+
+	// deps, if not nil, records the cache key of each directory loaded,
+	// or an empty string if it has none.
+	depsMu sync.Mutex
+	deps   map[string]string
+}
+
+func newLoader(ctx context.Context, fs FS, opts *LoadOptions) *loader {
+	parallelism := 0
+	var cache *Cache
+	if opts != nil {
+		parallelism = opts.Parallelism
+		cache = opts.Cache
+	}
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+	return &loader{
+		ctx:   ctx,
+		fs:    fs,
+		limit: make(limiter, parallelism),
+		cache: cache,
+	}
+}
+
+// loadModule loads the module in the given directory, or returns the module
+// loaded before from the same files if the loader has a cache.
+func (l *loader) loadModule(dir string) (*Module, Diagnostics) {
+	files, readDiags := readModuleFiles(l, dir)
+	var key string
+	if l.cache != nil || l.deps != nil {
+		key = moduleCacheKey(dir, files, readDiags)
+		l.recordDep(dir, key)
+	}
+	if l.cache != nil && key != "" {
+		if module, ok := l.cache.getModule(key); ok {
+			return module, module.Diagnostics
+		}
+	}
+
+	module, diags := l.parseModule(dir, files, readDiags)
+	if l.cache != nil && key != "" && l.ctx.Err() == nil {
+		l.cache.put(key, module)
+	}
+	return module, diags
+}
+
+// parseModule parses the given files of the module in the given directory,
+// falling back on the legacy HCL parser if that fails.
+func (l *loader) parseModule(dir string, files []moduleFile, readDiags hcl.Diagnostics) (*Module, Diagnostics) {
+	// For broad compatibility here we actually have two separate loader
+	// codepaths. The main one uses the new HCL parser and API and is intended
+	// for configurations from Terraform 0.12 onwards (though will work for
+	// many older configurations too), but we'll also fall back on one that
+	// uses the _old_ HCL implementation so we can deal with some edge-cases
+	// that are not valid in new HCL.
+
+	module, diags := loadModule(l, dir, files, readDiags)
+	if diags.HasErrors() && l.ctx.Err() == nil {
+		// Try using the legacy HCL parser and see if we fare better.
+		var legacyModule *Module
+		var legacyDiags Diagnostics
+		l.do(func() {
+			legacyModule, legacyDiags = loadModuleLegacyHCL(l.fs, dir)
+		})
+		if legacyModule != nil && !legacyDiags.HasErrors() {
+			legacyModule.init(legacyDiags)
+			return legacyModule, legacyDiags
+		}
+	}
+
+	module.init(diags)
+	return module, diags
+}
+
+// recordDep records the cache key of the given directory, if the loader is
+// recording them.
+func (l *loader) recordDep(dir, key string) {
+	l.depsMu.Lock()
+	defer l.depsMu.Unlock()
+	if l.deps != nil {
+		l.deps[dir] = key
+	}
+}
+
+// do calls the given function once fewer than the limit of other calls are
+// running, unless the loader's context is done first. It reports whether
+// the function was called.
+func (l *loader) do(fn func()) bool {
+	select {
+	case l.limit <- struct{}{}:
+	case <-l.ctx.Done():
+		return false
+	}
+	defer func() { <-l.limit }()
+	if l.ctx.Err() != nil {
+		return false
+	}
+	fn()
+	return true
+}
+
+// canceled returns an error if the loader's context is done, for the
+// caller of a Context loading function to report once.
+func (l *loader) canceled() Diagnostics {
+	err := l.ctx.Err()
+	if err == nil {
+		return nil
+	}
+	return Diagnostics{
+		{
+			Severity: DiagError,
+			Summary:  "Loading canceled",
+			Code:     DiagCodeCanceled,
+			Detail:   fmt.Sprintf("Loading stopped before it was complete: %s.", err),
+		},
+	}
+}
+
+// limiter is a counting semaphore.
+type limiter chan struct{}
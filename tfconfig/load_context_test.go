@@ -0,0 +1,129 @@
+package tfconfig
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadModuleContext(t *testing.T) {
+	fixtures, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range fixtures {
+		if !info.IsDir() {
+			continue
+		}
+		dir := filepath.Join("testdata", info.Name())
+		t.Run(info.Name(), func(t *testing.T) {
+			want, wantDiags := LoadModule(dir)
+			got, gotDiags := LoadModuleContext(context.Background(), NewOsFs(), dir, &LoadOptions{Parallelism: 4})
+			assertSameJSON(t, got, want)
+			assertSameJSON(t, gotDiags, wantDiags)
+		})
+	}
+}
+
+func TestLoadModuleTreeContext(t *testing.T) {
+	dir := filepath.Join("testdata", "module-tree")
+	want, wantDiags := LoadModuleTree(dir)
+	for i := 0; i < 10; i++ {
+		got, gotDiags := LoadModuleTreeContext(context.Background(), NewOsFs(), dir, nil)
+		assertSameJSON(t, got, want)
+		assertSameJSON(t, gotDiags, wantDiags)
+	}
+}
+
+func TestScanContext(t *testing.T) {
+	want := Scan(NewOsFs(), "testdata", nil)
+	if len(want.Modules) < 10 {
+		t.Fatalf("found only %d modules", len(want.Modules))
+	}
+	for i := 0; i < 3; i++ {
+		got := ScanContext(context.Background(), NewOsFs(), "testdata", &ScanOptions{LoadOptions: LoadOptions{Parallelism: 8}})
+		assertSameJSON(t, got, want)
+	}
+}
+
+func TestLoadOptionsParallelism(t *testing.T) {
+	for _, parallelism := range []int{1, 3} {
+		fs := &concurrencyFs{FS: NewOsFs(), delay: time.Millisecond}
+		ScanContext(context.Background(), fs, "testdata", &ScanOptions{LoadOptions: LoadOptions{Parallelism: parallelism}})
+		if fs.max > parallelism {
+			t.Errorf("%d files were read at once with a parallelism of %d", fs.max, parallelism)
+		}
+		if parallelism > 1 && fs.max < 2 {
+			t.Errorf("files were read one at a time with a parallelism of %d", parallelism)
+		}
+	}
+}
+
+func TestLoadModuleContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dir := filepath.Join("testdata", "module-tree")
+
+	_, diags := LoadModuleContext(ctx, NewOsFs(), dir, nil)
+	if len(diags) != 1 || diags[0].Code != DiagCodeCanceled {
+		t.Errorf("wrong diagnostics from LoadModuleContext: %#v", diags)
+	}
+	tree, diags := LoadModuleTreeContext(ctx, NewOsFs(), dir, nil)
+	if len(diags) != 1 || diags[0].Code != DiagCodeCanceled || len(tree.Children) != 0 {
+		t.Errorf("wrong result from LoadModuleTreeContext: %#v", diags)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	fs := &concurrencyFs{FS: NewOsFs(), delay: 2 * time.Millisecond}
+	report := ScanContext(ctx, fs, "testdata", &ScanOptions{LoadOptions: LoadOptions{Parallelism: 1}})
+	if len(report.Modules) != 0 || len(report.Diagnostics) != 1 || report.Diagnostics[0].Code != DiagCodeCanceled {
+		t.Errorf("wrong result from ScanContext: %d modules, %#v", len(report.Modules), report.Diagnostics)
+	}
+}
+
+func assertSameJSON(t *testing.T, got, want interface{}) {
+	t.Helper()
+	gotJSON, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON, err := json.MarshalIndent(want, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", gotJSON, wantJSON)
+	}
+}
+
+// concurrencyFs is an FS that records the largest number of files read from
+// it at once, taking the given time to read each.
+type concurrencyFs struct {
+	FS
+	delay time.Duration
+
+	mu      sync.Mutex
+	reading int
+	max     int
+}
+
+func (fs *concurrencyFs) ReadFile(name string) ([]byte, error) {
+	fs.mu.Lock()
+	fs.reading++
+	if fs.reading > fs.max {
+		fs.max = fs.reading
+	}
+	fs.mu.Unlock()
+
+	time.Sleep(fs.delay)
+
+	fs.mu.Lock()
+	fs.reading--
+	fs.mu.Unlock()
+	return fs.FS.ReadFile(name)
+}
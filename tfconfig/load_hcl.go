@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 
@@ -14,42 +15,99 @@ import (
 	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
-func loadModule(fs FS, dir string) (*Module, Diagnostics) {
+func loadModule(l *loader, dir string, files []moduleFile, diags hcl.Diagnostics) (*Module, Diagnostics) {
 	mod := NewModule(dir)
-	primaryPaths, diags := dirFiles(fs, dir)
 
-	parser := hclparse.NewParser()
-
-	for _, filename := range primaryPaths {
-		var file *hcl.File
-		var fileDiags hcl.Diagnostics
-
-		b, err := fs.ReadFile(filename)
-		if err != nil {
-			diags = append(diags, &hcl.Diagnostic{
-				Severity: hcl.DiagError,
-				Summary:  "Failed to read file",
-				Detail:   fmt.Sprintf("The configuration file %q could not be read.", filename),
-			})
+	// The files are parsed concurrently, each with its own parser, but they
+	// are added to the module in order so that the result doesn't depend on
+	// how they were scheduled.
+	parsed := make([]*hcl.File, len(files))
+	parseDiags := make([]hcl.Diagnostics, len(files))
+	var wg sync.WaitGroup
+	for i, f := range files {
+		if f.src == nil {
 			continue
 		}
-		if strings.HasSuffix(filename, ".json") {
-			file, fileDiags = parser.ParseJSON(b, filename)
-		} else {
-			file, fileDiags = parser.ParseHCL(b, filename)
-		}
-		diags = append(diags, fileDiags...)
-		if file == nil {
+		wg.Add(1)
+		go func(i int, f moduleFile) {
+			defer wg.Done()
+			l.do(func() {
+				parser := hclparse.NewParser()
+				if strings.HasSuffix(f.name, ".json") {
+					parsed[i], parseDiags[i] = parser.ParseJSON(f.src, f.name)
+				} else {
+					parsed[i], parseDiags[i] = parser.ParseHCL(f.src, f.name)
+				}
+			})
+		}(i, f)
+	}
+	wg.Wait()
+
+	for i, f := range files {
+		diags = append(diags, f.diags...)
+		diags = append(diags, parseDiags[i]...)
+		if parsed[i] == nil {
 			continue
 		}
 
-		contentDiags := LoadModuleFromFile(file, mod)
+		contentDiags := LoadModuleFromFile(parsed[i], mod)
 		diags = append(diags, contentDiags...)
 	}
 
 	return mod, diagnosticsHCL(diags)
 }
 
+// moduleFile is a configuration file of a module, as read by
+// readModuleFiles.
+type moduleFile struct {
+	name string
+
+	// src is the content of the file, or nil if it wasn't read because of
+	// an error, recorded in diags, or because loading was canceled.
+	src   []byte
+	diags hcl.Diagnostics
+}
+
+// readModuleFiles concurrently reads the configuration files in the given
+// directory, in the order that they are loaded.
+func readModuleFiles(l *loader, dir string) ([]moduleFile, hcl.Diagnostics) {
+	var primaryPaths []string
+	var diags hcl.Diagnostics
+	l.do(func() {
+		primaryPaths, diags = dirFiles(l.fs, dir)
+	})
+
+	files := make([]moduleFile, len(primaryPaths))
+	var wg sync.WaitGroup
+	for i, filename := range primaryPaths {
+		files[i].name = filename
+		wg.Add(1)
+		go func(f *moduleFile) {
+			defer wg.Done()
+			l.do(func() {
+				b, err := l.fs.ReadFile(f.name)
+				if err != nil {
+					f.diags = hcl.Diagnostics{
+						{
+							Severity: hcl.DiagError,
+							Summary:  "Failed to read file",
+							Detail:   fmt.Sprintf("The configuration file %q could not be read.", f.name),
+							Extra:    diagCodeExtra(DiagCodeReadFile),
+						},
+					}
+					return
+				}
+				if b == nil {
+					b = []byte{}
+				}
+				f.src = b
+			})
+		}(&files[i])
+	}
+	wg.Wait()
+	return files, diags
+}
+
 // LoadModuleFromFile reads given file, interprets it and stores in given Module
 // This is useful for any caller which does tokenization/parsing on its own
 // e.g. because it will reuse these parsed files later for more detailed
@@ -91,6 +149,7 @@ func LoadModuleFromFile(file *hcl.File, mod *Module) hcl.Diagnostics {
 										Summary:  "Multiple provider source attributes",
 										Detail:   fmt.Sprintf("Found multiple source attributes for provider %s: %q, %q", name, source, req.Source),
 										Subject:  &innerBlock.DefRange,
+										Extra:    diagCodeExtra(DiagCodeProviderSource),
 									})
 								} else {
 									mod.RequiredProviders[name].Source = req.Source
@@ -241,6 +300,13 @@ func LoadModuleFromFile(file *hcl.File, mod *Module) hcl.Diagnostics {
 				// 	o.Value = "value of output cannot be traversed"
 				// }
 
+				o.References = referencesForExpr(attr.Expr)
+			}
+
+			if attr, defined := content.Attributes["depends_on"]; defined {
+				dependsOn, depDiags := dependsOnForExpr(attr.Expr)
+				diags = append(diags, depDiags...)
+				o.DependsOn = dependsOn
 			}
 
 		case "provider":
@@ -274,8 +340,9 @@ func LoadModuleFromFile(file *hcl.File, mod *Module) hcl.Diagnostics {
 			}
 
 			mod.ProviderConfigs[providerKey] = &ProviderConfig{
-				Name:  name,
-				Alias: alias,
+				Name:       name,
+				Alias:      alias,
+				References: referencesForBody(block.Body, "version", "alias"),
 			}
 
 		case "resource", "data":
@@ -304,9 +371,16 @@ func LoadModuleFromFile(file *hcl.File, mod *Module) hcl.Diagnostics {
 				Type:       typeName,
 				Name:       name,
 				Attributes: resourceAttributes,
+				References: referencesForBody(block.Body, "provider", "depends_on"),
 				Pos:        sourcePosHCL(block.DefRange),
 			}
 
+			if attr, defined := content.Attributes["depends_on"]; defined {
+				dependsOn, depDiags := dependsOnForExpr(attr.Expr)
+				diags = append(diags, depDiags...)
+				r.DependsOn = dependsOn
+			}
+
 			var resourcesMap map[string]*Resource
 
 			switch block.Type {
@@ -361,6 +435,7 @@ func LoadModuleFromFile(file *hcl.File, mod *Module) hcl.Diagnostics {
 						Summary:  "Invalid provider reference",
 						Detail:   "Provider argument requires a provider name followed by an optional alias, like \"aws.foo\".",
 						Subject:  attr.Expr.Range().Ptr(),
+						Extra:    diagCodeExtra(DiagCodeProviderReference),
 					})
 				}
 			} else {
@@ -396,6 +471,13 @@ func LoadModuleFromFile(file *hcl.File, mod *Module) hcl.Diagnostics {
 				Name:       block.Labels[0],
 				Pos:        sourcePosHCL(block.DefRange),
 				Attributes: moduleAttributes,
+				References: referencesForBody(block.Body, "source", "version", "providers", "depends_on"),
+			}
+
+			if attr, defined := content.Attributes["depends_on"]; defined {
+				dependsOn, depDiags := dependsOnForExpr(attr.Expr)
+				diags = append(diags, depDiags...)
+				mc.DependsOn = dependsOn
 			}
 
 			// check if this is overriding an existing module
@@ -424,6 +506,42 @@ func LoadModuleFromFile(file *hcl.File, mod *Module) hcl.Diagnostics {
 				mc.Version = version
 			}
 
+		case "locals":
+
+			attrs, attrsDiags := block.Body.JustAttributes()
+			diags = append(diags, attrsDiags...)
+
+			for name, attr := range attrs {
+				pos := sourcePosHCL(attr.Range)
+				mod.Locals[name] = &Local{
+					Name:       name,
+					References: referencesForExpr(attr.Expr),
+					Pos:        &pos,
+				}
+			}
+
+		case "moved":
+
+			content, _, contentDiags := block.Body.PartialContent(movedSchema)
+			diags = append(diags, contentDiags...)
+			if contentDiags.HasErrors() {
+				continue
+			}
+
+			from, fromDiags := movedAddr(content.Attributes["from"].Expr)
+			diags = append(diags, fromDiags...)
+			to, toDiags := movedAddr(content.Attributes["to"].Expr)
+			diags = append(diags, toDiags...)
+			if fromDiags.HasErrors() || toDiags.HasErrors() {
+				continue
+			}
+
+			mod.Moved = append(mod.Moved, &Moved{
+				From: from,
+				To:   to,
+				Pos:  sourcePosHCL(block.DefRange),
+			})
+
 		default:
 			// Should never happen because our cases above should be
 			// exhaustive for our schema.
@@ -5,6 +5,8 @@ import (
 
 	legacyhcl "github.com/hashicorp/hcl"
 	legacyast "github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 )
 
 func loadModuleLegacyHCL(fs FS, dir string) (*Module, Diagnostics) {
@@ -147,6 +149,8 @@ func loadModuleLegacyHCL(fs FS, dir string) (*Module, Diagnostics) {
 					Name:        name,
 					Description: block.Description,
 					Sensitive:   block.Sensitive,
+					References:  legacyHCLReferences(item.Val, filename, "depends_on"),
+					DependsOn:   legacyHCLDependsOn(item.Val, filename),
 					Pos:         &pos,
 				}
 				if _, exists := mod.Outputs[name]; exists {
@@ -208,7 +212,9 @@ func loadModuleLegacyHCL(fs FS, dir string) (*Module, Diagnostics) {
 							Name:  providerName,
 							Alias: providerAlias,
 						},
-						Pos: sourcePosLegacyHCL(item.Pos(), filename),
+						References: legacyHCLReferences(item.Val, filename, "provider", "depends_on"),
+						DependsOn:  legacyHCLDependsOn(item.Val, filename),
+						Pos:        sourcePosLegacyHCL(item.Pos(), filename),
 					}
 					key := r.MapKey()
 					if _, exists := rMap[key]; exists {
@@ -243,10 +249,12 @@ func loadModuleLegacyHCL(fs FS, dir string) (*Module, Diagnostics) {
 				}
 
 				mc := &ModuleCall{
-					Name:    name,
-					Source:  block.Source,
-					Version: block.Version,
-					Pos:     sourcePosLegacyHCL(item.Pos(), filename),
+					Name:       name,
+					Source:     block.Source,
+					Version:    block.Version,
+					References: legacyHCLReferences(item.Val, filename, "source", "version", "providers", "depends_on"),
+					DependsOn:  legacyHCLDependsOn(item.Val, filename),
+					Pos:        sourcePosLegacyHCL(item.Pos(), filename),
 				}
 				// it's possible this module call is from an override file
 				if origMod, exists := mod.ModuleCalls[name]; exists {
@@ -262,6 +270,7 @@ func loadModuleLegacyHCL(fs FS, dir string) (*Module, Diagnostics) {
 			providerConfigs = providerConfigs.Children()
 			type ProviderBlock struct {
 				Version string
+				Alias   string
 			}
 
 			for _, item := range providerConfigs.Items {
@@ -287,6 +296,38 @@ func loadModuleLegacyHCL(fs FS, dir string) (*Module, Diagnostics) {
 				if block.Version != "" {
 					mod.RequiredProviders[name].VersionConstraints = append(mod.RequiredProviders[name].VersionConstraints, block.Version)
 				}
+
+				providerKey := name
+				if block.Alias != "" {
+					providerKey = name + "." + block.Alias
+				}
+				mod.ProviderConfigs[providerKey] = &ProviderConfig{
+					Name:       name,
+					Alias:      block.Alias,
+					References: legacyHCLReferences(item.Val, filename, "version", "alias"),
+				}
+			}
+		}
+
+		for _, item := range list.Filter("locals").Items {
+			obj, ok := item.Val.(*legacyast.ObjectType)
+			if !ok {
+				return nil, diagnosticsErrorf("locals block at %s is not an object", item.Pos())
+			}
+			for _, attr := range obj.List.Items {
+				if len(attr.Keys) != 1 {
+					continue
+				}
+				name, ok := attr.Keys[0].Token.Value().(string)
+				if !ok {
+					continue
+				}
+				pos := sourcePosLegacyHCL(attr.Pos(), filename)
+				mod.Locals[name] = &Local{
+					Name:       name,
+					References: legacyHCLReferences(attr.Val, filename),
+					Pos:        &pos,
+				}
 			}
 		}
 	}
@@ -294,6 +335,83 @@ func loadModuleLegacyHCL(fs FS, dir string) (*Module, Diagnostics) {
 	return mod, nil
 }
 
+// legacyHCLReferences returns the sorted, unique addresses of the objects
+// referred to by the interpolation sequences in the strings of the given
+// legacy HCL value, excluding the named top-level arguments, as
+// referencesForBody does for the main parser.
+func legacyHCLReferences(node legacyast.Node, filename string, exclude ...string) []string {
+	skip := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		skip[name] = true
+	}
+	return referencesForTraversals(legacyHCLTraversals(node, filename, skip))
+}
+
+func legacyHCLTraversals(node legacyast.Node, filename string, skip map[string]bool) []hcl.Traversal {
+	var traversals []hcl.Traversal
+	switch node := node.(type) {
+	case *legacyast.ObjectType:
+		for _, item := range node.List.Items {
+			if len(item.Keys) > 0 {
+				name, _ := item.Keys[0].Token.Value().(string)
+				if skip[name] || name == "lifecycle" {
+					continue
+				}
+			}
+			traversals = append(traversals, legacyHCLTraversals(item.Val, filename, nil)...)
+		}
+	case *legacyast.ListType:
+		for _, elem := range node.List {
+			traversals = append(traversals, legacyHCLTraversals(elem, filename, nil)...)
+		}
+	case *legacyast.LiteralType:
+		str, ok := node.Token.Value().(string)
+		if !ok || !strings.Contains(str, "${") {
+			break
+		}
+		// Legacy interpolation sequences are valid template syntax.
+		expr, diags := hclsyntax.ParseTemplate([]byte(str), filename, hcl.Pos{Line: node.Token.Pos.Line, Column: node.Token.Pos.Column})
+		if !diags.HasErrors() {
+			traversals = append(traversals, expr.Variables()...)
+		}
+	}
+	return traversals
+}
+
+// legacyHCLDependsOn returns the addresses listed as strings in the
+// depends_on argument of the given legacy HCL value.
+func legacyHCLDependsOn(node legacyast.Node, filename string) []string {
+	obj, ok := node.(*legacyast.ObjectType)
+	if !ok {
+		return nil
+	}
+	var ret []string
+	for _, item := range obj.List.Filter("depends_on").Items {
+		list, ok := item.Val.(*legacyast.ListType)
+		if !ok {
+			continue
+		}
+		for _, elem := range list.List {
+			lit, ok := elem.(*legacyast.LiteralType)
+			if !ok {
+				continue
+			}
+			str, ok := lit.Token.Value().(string)
+			if !ok {
+				continue
+			}
+			traversal, diags := hclsyntax.ParseTraversalAbs([]byte(str), filename, hcl.Pos{Line: lit.Token.Pos.Line, Column: lit.Token.Pos.Column})
+			if diags.HasErrors() {
+				continue
+			}
+			if addr := referenceAddr(traversal); addr != "" {
+				ret = append(ret, addr)
+			}
+		}
+	}
+	return ret
+}
+
 // unwrapLegacyHCLObjectKeysFromJSON cleans up an edge case that can occur when
 // parsing JSON as input: if we're parsing JSON then directly nested
 // items will show up as additional "keys".
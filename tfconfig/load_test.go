@@ -1,6 +1,7 @@
 package tfconfig
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
@@ -57,3 +58,113 @@ func TestLoadModule(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadIBMModuleDiagnostics(t *testing.T) {
+	path := filepath.Join("testdata", "nested-diagnostics")
+	module, diags := LoadIBMModule(path, "", map[string]interface{}{})
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+
+	filename := filepath.Join(path, "network", "subnet", "main.tf")
+	want := Diagnostics{
+		{
+			Severity: DiagError,
+			Summary:  "Invalid expression",
+			Detail:   "Expected the start of an expression, but found an invalid expression token.",
+			Code:     DiagCodeHCL,
+			Pos:      &SourcePos{Filename: filename, Line: 8},
+			Range: &SourceRange{
+				Filename: filename,
+				Start:    SourcePoint{Line: 8, Column: 9, Byte: 133},
+				End:      SourcePoint{Line: 9, Column: 1, Byte: 134},
+			},
+			Module: "module.network.module.subnet",
+		},
+	}
+	if diff := deep.Equal(diags, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+	if diff := deep.Equal(module.Diagnostics, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("module diagnostics: %s", problem)
+		}
+	}
+}
+
+func TestLoadIBMModuleFromFilesystem(t *testing.T) {
+	path := filepath.Join("testdata", "ibm-metadata")
+	src := []byte(`{
+  "Resources": {
+    "ibm_is_vpc": [{"name": "name", "description": "Name of the VPC"}],
+    "ibm_is_subnet": [{"name": "zone", "description": "Zone of the subnet", "options": "us-south-1,us-south-2"}]
+  }
+}`)
+
+	parsed, diags := ParseProviderMetadata(src)
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	read, diags := ReadProviderMetadata(bytes.NewReader(src))
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	tests := map[string]ProviderMetadata{
+		"parsed": parsed,
+		"read":   read,
+		"built": {
+			"Resources": map[string]interface{}{
+				"ibm_is_vpc": []interface{}{
+					map[string]interface{}{"name": "name", "description": "Name of the VPC"},
+				},
+				"ibm_is_subnet": []interface{}{
+					map[string]interface{}{"name": "zone", "description": "Zone of the subnet", "options": "us-south-1,us-south-2"},
+				},
+			},
+		},
+	}
+
+	required := true
+	want := map[string]*Variable{
+		"region": {
+			Name:          "region",
+			Type:          "string",
+			Description:   "Zone of the subnet",
+			Required:      &required,
+			Source:        []string{"module.subnet.ibm_is_subnet.this.zone"},
+			Pos:           &SourcePos{Filename: filepath.Join(path, "main.tf"), Line: 1},
+			AllowedValues: "us-south-1,us-south-2",
+		},
+		"vpc_name": {
+			Name:        "vpc_name",
+			Type:        "string",
+			Description: "Name of the VPC",
+			Required:    &required,
+			Source:      []string{"ibm_is_vpc.this.name"},
+			Pos:         &SourcePos{Filename: filepath.Join(path, "main.tf"), Line: 5},
+		},
+	}
+
+	for name, metadata := range tests {
+		t.Run(name, func(t *testing.T) {
+			module, diags := CheckForInitDirectoryAndLoadIBMModuleFromFilesystem(NewOsFs(), path, metadata)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			if diff := deep.Equal(module.Variables, want); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
+
+func TestParseProviderMetadataInvalid(t *testing.T) {
+	_, diags := ParseProviderMetadata([]byte(`{"Resources": `))
+	if len(diags) != 1 || diags[0].Code != DiagCodeMetadata {
+		t.Fatalf("wrong diagnostics %#v; want one with code %q", diags, DiagCodeMetadata)
+	}
+}
@@ -0,0 +1,13 @@
+package tfconfig
+
+// Local represents a single named value from a "locals" block in a
+// Terraform module.
+type Local struct {
+	Name string `json:"name"`
+
+	// References are the addresses of the objects the local value's
+	// expression refers to.
+	References []string `json:"references,omitempty"`
+
+	Pos *SourcePos `json:"pos,omitempty"`
+}
@@ -7,9 +7,48 @@ import (
 	"text/template"
 )
 
+// RenderMarkdown writes a markdown description of the given module. If
+// variable is true, only the module's variables and outputs are described.
 func RenderMarkdown(w io.Writer, module *Module, variable bool) error {
+	if variable {
+		return RenderWithTemplate(w, module, markdownVariableTemplate)
+	}
+	return RenderWithTemplate(w, module, markdownTemplate)
+}
+
+// RenderWithTemplate executes the given text/template source with the given
+// module as its data, writing the result to w.
+//
+// In addition to the standard template functions, templates can use the
+// functions returned by TemplateFuncs.
+func RenderWithTemplate(w io.Writer, module *Module, tmplText string) error {
 	tmpl := template.New("md")
-	tmpl.Funcs(template.FuncMap{
+	tmpl.Funcs(TemplateFuncs())
+	_, err := tmpl.Parse(tmplText)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, module)
+}
+
+// TemplateFuncs returns the functions available to templates executed by
+// RenderWithTemplate. These are:
+//
+//   - tt, commas, json, jsonIndent and severity, for formatting values
+//   - join, split, lower, upper, trimSpace and replace, for manipulating strings
+//   - isTrue, which dereferences a *bool, treating nil as false
+//   - sortedKeys, which returns the keys of any string-keyed map in order
+//   - sortedVariables, sortedOutputs, sortedResources and sortedModuleCalls,
+//     which return the values of the corresponding maps ordered by key
+//
+// Templates have access to all of the fields of Module, including the
+// metadata added to each variable by LoadIBMModule. The resources and
+// outputs of each child module call are only set for modules loaded by
+// LoadIBMModule, or by LoadModuleTree followed by
+// ModuleTree.AddChildContents; otherwise they are empty.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
 		"tt": func(s string) string {
 			return "`" + s + "`"
 		},
@@ -20,6 +59,10 @@ func RenderMarkdown(w io.Writer, module *Module, variable bool) error {
 			j, err := json.Marshal(v)
 			return string(j), err
 		},
+		"jsonIndent": func(v interface{}) (string, error) {
+			j, err := json.MarshalIndent(v, "", "  ")
+			return string(j), err
+		},
 		"severity": func(s DiagSeverity) string {
 			switch s {
 			case DiagError:
@@ -30,14 +73,49 @@ func RenderMarkdown(w io.Writer, module *Module, variable bool) error {
 				return ""
 			}
 		},
-	})
-	if variable {
-		template.Must(tmpl.Parse(markdownVariableTemplate))
-	} else {
-		template.Must(tmpl.Parse(markdownTemplate))
+		"join": func(sep string, s []string) string {
+			return strings.Join(s, sep)
+		},
+		"split":     strings.Split,
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"trimSpace": strings.TrimSpace,
+		"replace": func(old, new, s string) string {
+			return strings.Replace(s, old, new, -1)
+		},
+		"isTrue": func(b *bool) bool {
+			return b != nil && *b
+		},
+		"sortedKeys": SortedKeysOfMap,
+		"sortedVariables": func(m map[string]*Variable) []*Variable {
+			ret := make([]*Variable, 0, len(m))
+			for _, k := range SortedKeysOfMap(m) {
+				ret = append(ret, m[k])
+			}
+			return ret
+		},
+		"sortedOutputs": func(m map[string]*Output) []*Output {
+			ret := make([]*Output, 0, len(m))
+			for _, k := range SortedKeysOfMap(m) {
+				ret = append(ret, m[k])
+			}
+			return ret
+		},
+		"sortedResources": func(m map[string]*Resource) []*Resource {
+			ret := make([]*Resource, 0, len(m))
+			for _, k := range SortedKeysOfMap(m) {
+				ret = append(ret, m[k])
+			}
+			return ret
+		},
+		"sortedModuleCalls": func(m map[string]*ModuleCall) []*ModuleCall {
+			ret := make([]*ModuleCall, 0, len(m))
+			for _, k := range SortedKeysOfMap(m) {
+				ret = append(ret, m[k])
+			}
+			return ret
+		},
 	}
-
-	return tmpl.Execute(w, module)
 }
 
 const markdownTemplate = `
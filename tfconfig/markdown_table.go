@@ -0,0 +1,219 @@
+package tfconfig
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// MarkdownSortOrder selects the order in which RenderMarkdownTable lists the
+// rows of each table.
+type MarkdownSortOrder string
+
+const (
+	// SortByName orders rows by name, which is the default.
+	SortByName MarkdownSortOrder = "name"
+
+	// SortByRequired lists required input variables before optional ones,
+	// each ordered by name. Other tables are ordered by name.
+	SortByRequired MarkdownSortOrder = "required"
+
+	// SortByPosition orders rows by the position of their definitions in
+	// the module's source files.
+	SortByPosition MarkdownSortOrder = "position"
+)
+
+// MarkdownTableOptions customizes the output of RenderMarkdownTable.
+type MarkdownTableOptions struct {
+	SortBy MarkdownSortOrder
+}
+
+// markdownTableData is the data given to markdownTableTemplate, with each
+// map of the module flattened into a slice in the requested order.
+type markdownTableData struct {
+	*Module
+	SortedVariables        []*Variable
+	SortedOutputs          []*Output
+	SortedManagedResources []*Resource
+	SortedDataResources    []*Resource
+	SortedModuleCalls      []*ModuleCall
+}
+
+// RenderMarkdownTable writes a markdown description of the given module that
+// presents its variables, outputs, resources, data sources and child
+// modules as GitHub-flavored markdown tables.
+//
+// Unlike RenderMarkdown, the input variables table includes the metadata
+// added by LoadIBMModule, along with the resources each variable is passed
+// to.
+func RenderMarkdownTable(w io.Writer, module *Module, opts MarkdownTableOptions) error {
+	data := &markdownTableData{Module: module}
+
+	for _, k := range SortedKeysOfMap(module.Variables) {
+		data.SortedVariables = append(data.SortedVariables, module.Variables[k])
+	}
+	for _, k := range SortedKeysOfMap(module.Outputs) {
+		data.SortedOutputs = append(data.SortedOutputs, module.Outputs[k])
+	}
+	for _, k := range SortedKeysOfMap(module.ManagedResources) {
+		data.SortedManagedResources = append(data.SortedManagedResources, module.ManagedResources[k])
+	}
+	for _, k := range SortedKeysOfMap(module.DataResources) {
+		data.SortedDataResources = append(data.SortedDataResources, module.DataResources[k])
+	}
+	for _, k := range SortedKeysOfMap(module.ModuleCalls) {
+		data.SortedModuleCalls = append(data.SortedModuleCalls, module.ModuleCalls[k])
+	}
+
+	switch opts.SortBy {
+	case "", SortByName:
+		// Already sorted by name
+	case SortByRequired:
+		sort.SliceStable(data.SortedVariables, func(i, j int) bool {
+			return isRequired(data.SortedVariables[i]) && !isRequired(data.SortedVariables[j])
+		})
+	case SortByPosition:
+		sort.SliceStable(data.SortedVariables, func(i, j int) bool {
+			return sourcePosLess(data.SortedVariables[i].Pos, data.SortedVariables[j].Pos)
+		})
+		sort.SliceStable(data.SortedOutputs, func(i, j int) bool {
+			return sourcePosLess(data.SortedOutputs[i].Pos, data.SortedOutputs[j].Pos)
+		})
+		sort.SliceStable(data.SortedManagedResources, func(i, j int) bool {
+			return sourcePosLess(&data.SortedManagedResources[i].Pos, &data.SortedManagedResources[j].Pos)
+		})
+		sort.SliceStable(data.SortedDataResources, func(i, j int) bool {
+			return sourcePosLess(&data.SortedDataResources[i].Pos, &data.SortedDataResources[j].Pos)
+		})
+		sort.SliceStable(data.SortedModuleCalls, func(i, j int) bool {
+			return sourcePosLess(&data.SortedModuleCalls[i].Pos, &data.SortedModuleCalls[j].Pos)
+		})
+	default:
+		return fmt.Errorf("unsupported sort order %q", opts.SortBy)
+	}
+
+	tmpl := template.New("md")
+	tmpl.Funcs(TemplateFuncs())
+	tmpl.Funcs(template.FuncMap{
+		"cell": markdownTableCell,
+	})
+	template.Must(tmpl.Parse(markdownTableTemplate))
+
+	return tmpl.Execute(w, data)
+}
+
+func isRequired(v *Variable) bool {
+	return v.Required != nil && *v.Required
+}
+
+// sourcePosLess orders source positions by filename and then line, placing
+// unknown positions last.
+func sourcePosLess(a, b *SourcePos) bool {
+	switch {
+	case a == nil:
+		return false
+	case b == nil:
+		return true
+	case a.Filename != b.Filename:
+		return a.Filename < b.Filename
+	default:
+		return a.Line < b.Line
+	}
+}
+
+// markdownTableCell escapes the given string so that it can be used as the
+// content of a single markdown table cell.
+func markdownTableCell(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.Replace(s, "|", `\|`, -1)
+	s = strings.Replace(s, "\r\n", "<br>", -1)
+	return strings.Replace(s, "\n", "<br>", -1)
+}
+
+const markdownTableTemplate = `
+# Module {{ tt .Path }}
+
+{{- if .RequiredCore}}
+
+Core Version Constraints:
+{{- range .RequiredCore }}
+* {{ tt . }}
+{{- end}}{{end}}
+
+{{- if .RequiredProviders}}
+
+## Providers
+
+| Name | Source | Version |
+|------|--------|---------|
+{{- range $name, $req := .RequiredProviders }}
+| {{ tt $name }} | {{ if $req.Source }}{{ tt $req.Source }}{{ else }}n/a{{ end }} | {{ if $req.VersionConstraints }}{{ commas $req.VersionConstraints | cell | tt }}{{ else }}any{{ end }} |
+{{- end}}{{end}}
+
+{{- if .SortedVariables}}
+
+## Inputs
+
+| Name | Description | Type | Default | Required | Sensitive | Allowed values | Cloud data type | Used by |
+|------|-------------|------|---------|:--------:|:---------:|----------------|-----------------|---------|
+{{- range .SortedVariables }}
+| {{ tt .Name }} | {{ cell .Description }} | {{ if .Type }}{{ cell .Type | tt }}{{ else }}any{{ end }} | {{ if isTrue .Required }}n/a{{ else }}{{ json .Default | cell | tt }}{{ end }} | {{ if isTrue .Required }}yes{{ else }}no{{ end }} | {{ if isTrue .Sensitive }}yes{{ else }}no{{ end }} | {{ cell .AllowedValues }} | {{ cell .CloudDataType }} | {{ range $i, $s := .Source }}{{ if $i }}<br>{{ end }}{{ tt $s }}{{ end }} |
+{{- end}}{{end}}
+
+{{- if .SortedOutputs}}
+
+## Outputs
+
+| Name | Description | Type | Sensitive |
+|------|-------------|------|:---------:|
+{{- range .SortedOutputs }}
+| {{ tt .Name }} | {{ cell .Description }} | {{ if .Type }}{{ cell .Type | tt }}{{ end }} | {{ if .Sensitive }}yes{{ else }}no{{ end }} |
+{{- end}}{{end}}
+
+{{- if .SortedManagedResources}}
+
+## Resources
+
+| Name | Type | Provider |
+|------|------|----------|
+{{- range .SortedManagedResources }}
+| {{ printf "%s.%s" .Type .Name | tt }} | {{ tt .Type }} | {{ tt .Provider.Name }} |
+{{- end}}{{end}}
+
+{{- if .SortedDataResources}}
+
+## Data Sources
+
+| Name | Type | Provider |
+|------|------|----------|
+{{- range .SortedDataResources }}
+| {{ printf "data.%s.%s" .Type .Name | tt }} | {{ tt .Type }} | {{ tt .Provider.Name }} |
+{{- end}}{{end}}
+
+{{- if .SortedModuleCalls}}
+
+## Child Modules
+
+| Name | Source | Version |
+|------|--------|---------|
+{{- range .SortedModuleCalls }}
+| {{ tt .Name }} | {{ tt .Source }} | {{ if .Version }}{{ tt .Version }}{{ end }} |
+{{- end}}{{end}}
+
+{{- if .Diagnostics}}
+
+## Problems
+{{- range .Diagnostics }}
+
+## {{ severity .Severity }}{{ .Summary }}{{ if .Pos }}
+
+(at {{ tt .Pos.Filename }} line {{ .Pos.Line }}{{ end }})
+{{ if .Detail }}
+{{ .Detail }}
+{{- end }}
+
+{{- end}}{{end}}
+
+`
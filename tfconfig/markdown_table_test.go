@@ -0,0 +1,100 @@
+package tfconfig
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestRenderMarkdownTable(t *testing.T) {
+	fixturesDir := "testdata"
+	testDirs, err := ioutil.ReadDir(fixturesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, info := range testDirs {
+		if !info.IsDir() {
+			continue
+		}
+
+		t.Run(info.Name(), func(t *testing.T) {
+			name := info.Name()
+			path := filepath.Join(fixturesDir, name)
+
+			fullPath := filepath.Join(path, name+".out.table.md")
+			expected, err := ioutil.ReadFile(fullPath)
+			if err != nil {
+				t.Skipf("%q not found, skipping test", fullPath)
+			}
+
+			module, _ := LoadModule(path)
+			if module == nil {
+				t.Fatalf("result object is nil; want a real object")
+			}
+
+			var buf bytes.Buffer
+			err = RenderMarkdownTable(&buf, module, MarkdownTableOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(buf.String(), string(expected)); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownTableSortBy(t *testing.T) {
+	module, _ := LoadModule(filepath.Join("testdata", "variable-types"))
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+
+	tests := map[MarkdownSortOrder][]string{
+		SortByName: {
+			"bool_default_false", "list", "list_default_empty", "list_json", "map",
+			"number_default_zero", "object_default_empty", "primitive",
+			"string_default_empty", "string_default_null",
+		},
+		SortByRequired: {
+			"list", "list_json", "map", "primitive",
+			"bool_default_false", "list_default_empty", "number_default_zero",
+			"object_default_empty", "string_default_empty", "string_default_null",
+		},
+		SortByPosition: {
+			"primitive", "list", "map", "string_default_empty", "string_default_null",
+			"list_default_empty", "object_default_empty", "number_default_zero",
+			"bool_default_false", "list_json",
+		},
+	}
+
+	for sortBy, want := range tests {
+		t.Run(string(sortBy), func(t *testing.T) {
+			var buf bytes.Buffer
+			err := RenderMarkdownTable(&buf, module, MarkdownTableOptions{SortBy: sortBy})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if strings.HasPrefix(line, "| `") {
+					got = append(got, strings.Trim(strings.Split(line, " | ")[0], "| `"))
+				}
+			}
+			if diff := deep.Equal(got, want); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
@@ -51,3 +51,65 @@ func TestRenderMarkdown(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderWithTemplate(t *testing.T) {
+	module, _ := LoadModule(filepath.Join("testdata", "basics"))
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+
+	tmpl := `{{ range sortedVariables .Variables -}}
+{{ .Name | lower }}: {{ if isTrue .Required }}required{{ else }}{{ json .Default }}{{ end }}
+{{ end -}}
+{{ join "," (sortedKeys .Outputs) }}
+`
+	var buf bytes.Buffer
+	err := RenderWithTemplate(&buf, module, tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `a: "A default"
+b: required
+c: required
+A,B,C
+`
+	if diff := deep.Equal(buf.String(), want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+
+	err = RenderWithTemplate(&buf, module, "{{ .Unclosed ")
+	if err == nil {
+		t.Errorf("expected error for invalid template")
+	}
+}
+
+func TestRenderWithTemplateChildContents(t *testing.T) {
+	tmpl := `{{ range sortedModuleCalls .ModuleCalls -}}
+{{ .Name }}: {{ join "," (sortedKeys .ManagedResources) }} / {{ join "," (sortedKeys .Outputs) }}
+{{ end -}}
+`
+	module, _ := LoadModule(filepath.Join("testdata", "module-tree"))
+	var buf bytes.Buffer
+	if err := RenderWithTemplate(&buf, module, tmpl); err != nil {
+		t.Fatal(err)
+	}
+	// Without a tree, the contents of child modules are unknown.
+	want := "missing:  / \nnetwork:  / \nstorage:  / \n"
+	if got := buf.String(); got != want {
+		t.Errorf("wrong result\ngot:  %q\nwant: %q", got, want)
+	}
+
+	tree, _ := LoadModuleTree(filepath.Join("testdata", "module-tree"))
+	tree.AddChildContents()
+	buf.Reset()
+	if err := RenderWithTemplate(&buf, tree.Module, tmpl); err != nil {
+		t.Fatal(err)
+	}
+	want = "missing:  / \nnetwork: ibm_is_vpc.this / subnet_id\nstorage:  / bucket\n"
+	if got := buf.String(); got != want {
+		t.Errorf("wrong result\ngot:  %q\nwant: %q", got, want)
+	}
+}
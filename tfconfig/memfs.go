@@ -0,0 +1,222 @@
+package tfconfig
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+	"time"
+)
+
+// memFs is a read-only in-memory io/fs.FS, used to hold the contents of
+// archives that cannot be read in place. Directories are created implicitly
+// for the files added to it. The contents of files may be read lazily, when
+// they are first opened.
+type memFs struct {
+	entries map[string]*memEntry
+}
+
+// memEntry is a file or directory in a memFs. It describes itself as both
+// an fs.FileInfo and an fs.DirEntry.
+type memEntry struct {
+	name     string
+	size     int64
+	mode     fs.FileMode
+	modTime  time.Time
+	children map[string]*memEntry
+
+	// mu guards data and load. If load is set, data is yet to be read.
+	mu   sync.Mutex
+	data []byte
+	load func() ([]byte, error)
+}
+
+func newMemFs() *memFs {
+	return &memFs{
+		entries: map[string]*memEntry{
+			".": {name: ".", mode: fs.ModeDir | 0755, children: make(map[string]*memEntry)},
+		},
+	}
+}
+
+// addFile adds a file with the given slash-separated path, which must be
+// valid for fs.ValidPath, replacing any existing file with that path.
+func (m *memFs) addFile(name string, data []byte, mode fs.FileMode, modTime time.Time) {
+	m.add(name, &memEntry{
+		name:    path.Base(name),
+		size:    int64(len(data)),
+		data:    data,
+		mode:    mode &^ fs.ModeType,
+		modTime: modTime,
+	})
+}
+
+// addLazyFile is a variant of addFile for a file of the given size whose
+// contents are read with the given function when it is first opened.
+func (m *memFs) addLazyFile(name string, size int64, load func() ([]byte, error), mode fs.FileMode, modTime time.Time) {
+	m.add(name, &memEntry{
+		name:    path.Base(name),
+		size:    size,
+		load:    load,
+		mode:    mode &^ fs.ModeType,
+		modTime: modTime,
+	})
+}
+
+// addDir adds a directory with the given slash-separated path, which must
+// be valid for fs.ValidPath, if there isn't one already.
+func (m *memFs) addDir(name string, mode fs.FileMode, modTime time.Time) {
+	if e, ok := m.entries[name]; ok && e.IsDir() {
+		return
+	}
+	m.add(name, &memEntry{
+		name:     path.Base(name),
+		mode:     fs.ModeDir | mode.Perm(),
+		modTime:  modTime,
+		children: make(map[string]*memEntry),
+	})
+}
+
+func (m *memFs) add(name string, e *memEntry) {
+	parent := path.Dir(name)
+	m.addDir(parent, 0755, e.modTime)
+	m.entries[parent].children[e.name] = e
+	m.entries[name] = e
+}
+
+func (m *memFs) Open(name string) (fs.File, error) {
+	e, err := m.entry("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := e.open()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return f, nil
+}
+
+func (m *memFs) ReadFile(name string) ([]byte, error) {
+	e, err := m.entry("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	data, err := e.contents()
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (m *memFs) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := m.entry("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return e.sortedChildren(), nil
+}
+
+func (m *memFs) entry(op, name string) (*memEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	e, ok := m.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return e, nil
+}
+
+// open returns an open file reading the entry.
+func (e *memEntry) open() (fs.File, error) {
+	if e.IsDir() {
+		return &memDir{memEntry: e, entries: e.sortedChildren()}, nil
+	}
+	data, err := e.contents()
+	if err != nil {
+		return nil, err
+	}
+	return &memFile{e: e, r: bytes.NewReader(data)}, nil
+}
+
+// contents returns the contents of a file entry, reading them first if
+// they were added lazily.
+func (e *memEntry) contents() ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.load != nil {
+		data, err := e.load()
+		if err != nil {
+			return nil, err
+		}
+		e.data, e.load = data, nil
+	}
+	return e.data, nil
+}
+
+func (e *memEntry) sortedChildren() []fs.DirEntry {
+	ret := make([]fs.DirEntry, 0, len(e.children))
+	for _, name := range SortedKeysOfMap(e.children) {
+		ret = append(ret, e.children[name])
+	}
+	return ret
+}
+
+func (e *memEntry) Name() string               { return e.name }
+func (e *memEntry) Size() int64                { return e.size }
+func (e *memEntry) Mode() fs.FileMode          { return e.mode }
+func (e *memEntry) ModTime() time.Time         { return e.modTime }
+func (e *memEntry) IsDir() bool                { return e.mode.IsDir() }
+func (e *memEntry) Sys() interface{}           { return nil }
+func (e *memEntry) Type() fs.FileMode          { return e.mode.Type() }
+func (e *memEntry) Info() (fs.FileInfo, error) { return e, nil }
+
+// memFile is an open file in a memFs. Its entry and reader are named
+// fields rather than embedded, as both have a Size method.
+type memFile struct {
+	e *memEntry
+	r *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error)                   { return f.e, nil }
+func (f *memFile) Read(p []byte) (int, error)                   { return f.r.Read(p) }
+func (f *memFile) ReadAt(p []byte, off int64) (int, error)      { return f.r.ReadAt(p, off) }
+func (f *memFile) Seek(offset int64, whence int) (int64, error) { return f.r.Seek(offset, whence) }
+func (f *memFile) Close() error                                 { return nil }
+
+// memDir is an open directory in a memFs.
+type memDir struct {
+	*memEntry
+	entries []fs.DirEntry
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.memEntry, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		ret := d.entries
+		d.entries = nil
+		return ret, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	ret := d.entries[:n]
+	d.entries = d.entries[n:]
+	return ret, nil
+}
@@ -8,6 +8,7 @@ type Module struct {
 
 	Variables map[string]*Variable `json:"variables"`
 	Outputs   map[string]*Output   `json:"outputs"`
+	Locals    map[string]*Local    `json:"locals,omitempty"`
 
 	RequiredCore      []string                        `json:"required_core,omitempty"`
 	RequiredProviders map[string]*ProviderRequirement `json:"required_providers"`
@@ -16,6 +17,7 @@ type Module struct {
 	ManagedResources map[string]*Resource       `json:"managed_resources"`
 	DataResources    map[string]*Resource       `json:"data_resources"`
 	ModuleCalls      map[string]*ModuleCall     `json:"module_calls"`
+	Moved            []*Moved                   `json:"moved,omitempty"`
 
 	// Diagnostics records any errors and warnings that were detected during
 	// loading, primarily for inclusion in serialized forms of the module
@@ -31,6 +33,10 @@ type Metadata struct {
 type ProviderConfig struct {
 	Name  string `json:"name"`
 	Alias string `json:"alias,omitempty"`
+
+	// References are the addresses of the objects the provider
+	// configuration's arguments refer to.
+	References []string `json:"references,omitempty"`
 }
 
 // NewModule creates new Module representing Terraform module at the given path
@@ -39,6 +45,7 @@ func NewModule(path string) *Module {
 		Path:              path,
 		Variables:         make(map[string]*Variable),
 		Outputs:           make(map[string]*Output),
+		Locals:            make(map[string]*Local),
 		RequiredProviders: make(map[string]*ProviderRequirement),
 		ProviderConfigs:   make(map[string]*ProviderConfig),
 		ManagedResources:  make(map[string]*Resource),
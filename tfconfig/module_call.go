@@ -11,5 +11,10 @@ type ModuleCall struct {
 	DataResources    map[string]*Resource   `json:"data_resources"`
 	Outputs          map[string]*Output     `json:"outputs,omitempty"`
 
+	// References are the addresses of the objects the call's arguments
+	// refer to, and DependsOn are those given in its depends_on argument.
+	References []string `json:"references,omitempty"`
+	DependsOn  []string `json:"depends_on,omitempty"`
+
 	Pos SourcePos `json:"pos"`
 }
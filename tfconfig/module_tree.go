@@ -0,0 +1,251 @@
+package tfconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ModuleTree is a module together with the child modules it calls, as
+// loaded by LoadModuleTree.
+type ModuleTree struct {
+	// Address is the absolute address of the module, such as
+	// "module.network.module.vpc", or an empty string for the root module.
+	Address string `json:"address"`
+
+	Module *Module `json:"module"`
+
+	// Children are the trees for each of the module's calls that could be
+	// resolved to a local directory, keyed by the name of the call.
+	Children map[string]*ModuleTree `json:"children,omitempty"`
+}
+
+// maxModuleTreeDepth guards against infinite recursion when a module
+// indirectly calls itself via local paths, which Terraform would reject.
+const maxModuleTreeDepth = 32
+
+// LoadModuleTree loads the module in the given directory and then
+// recursively loads each of the child modules it calls.
+//
+// Child modules with local sources are loaded relative to their parent.
+// Other sources are found in the .terraform/modules directory created by
+// "terraform init", using its modules.json manifest when present. Calls
+// whose modules cannot be found produce warnings and are omitted from
+// Children.
+func LoadModuleTree(dir string) (*ModuleTree, Diagnostics) {
+	return LoadModuleTreeFromFilesystem(NewOsFs(), dir)
+}
+
+// LoadModuleTreeFromFilesystem is a variant of LoadModuleTree that reads
+// from the given FS.
+func LoadModuleTreeFromFilesystem(fs FS, dir string) (*ModuleTree, Diagnostics) {
+	return newLoader(context.Background(), fs, &LoadOptions{Parallelism: 1}).loadModuleTree(dir)
+}
+
+// LoadModuleTreeContext is a variant of LoadModuleTreeFromFilesystem that
+// loads the modules, and their files, concurrently as limited by the given
+// options, which may be nil. The result is the same however they are
+// scheduled.
+//
+// If the given context is canceled or its deadline passes before the tree
+// has been loaded, the modules not yet loaded are omitted and the returned
+// diagnostics include an error with the code DiagCodeCanceled.
+func LoadModuleTreeContext(ctx context.Context, fs FS, dir string, opts *LoadOptions) (*ModuleTree, Diagnostics) {
+	l := newLoader(ctx, fs, opts)
+	tree, diags := l.loadModuleTree(dir)
+	return tree, append(diags, l.canceled()...)
+}
+
+func (l *loader) loadModuleTree(dir string) (*ModuleTree, Diagnostics) {
+	var manifest map[string]string
+	l.do(func() {
+		manifest = loadModuleManifest(l.fs, dir)
+	})
+	return loadModuleTree(l, dir, manifest, dir, "", nil, 0)
+}
+
+func loadModuleTree(l *loader, rootDir string, manifest map[string]string, dir, key string, path []string, depth int) (*ModuleTree, Diagnostics) {
+	module, diags := l.loadModule(dir)
+	tree := &ModuleTree{
+		Address: moduleAddress(path),
+		Module:  module,
+	}
+	if depth >= maxModuleTreeDepth {
+		diags = append(diags, Diagnostic{
+			Severity: DiagError,
+			Summary:  "Module tree too deep",
+			Code:     DiagCodeModuleTreeDepth,
+			Detail:   fmt.Sprintf("Module %s is nested more than %d levels deep, which suggests that it calls itself.", tree.Address, maxModuleTreeDepth),
+		})
+		return tree, diags
+	}
+
+	// The children are loaded concurrently, and then added to the tree in
+	// order of call name.
+	type childResult struct {
+		name  string
+		tree  *ModuleTree
+		diags Diagnostics
+	}
+	names := SortedKeysOfMap(module.ModuleCalls)
+	children := make([]childResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		call := module.ModuleCalls[name]
+		childKey := name
+		if key != "" {
+			childKey = key + "." + name
+		}
+		childPath := append(append([]string(nil), path...), name)
+
+		var childDir string
+		var ok bool
+		if !l.do(func() {
+			childDir, ok = resolveModuleCallDir(l.fs, rootDir, manifest, dir, childKey, call)
+		}) {
+			break
+		}
+		children[i].name = name
+		if !ok {
+			pos := call.Pos
+			children[i].diags = Diagnostics{
+				{
+					Severity: DiagWarning,
+					Summary:  "Module not installed",
+					Code:     DiagCodeModuleNotInstalled,
+					Detail:   fmt.Sprintf("The source of %s (%q) could not be found locally. Run \"terraform init\" to install it.", moduleAddress(childPath), call.Source),
+					Pos:      &pos,
+				},
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(child *childResult) {
+			defer wg.Done()
+			child.tree, child.diags = loadModuleTree(l, rootDir, manifest, childDir, childKey, childPath, depth+1)
+		}(&children[i])
+	}
+	wg.Wait()
+
+	for _, child := range children {
+		diags = append(diags, child.diags...)
+		if child.tree == nil {
+			continue
+		}
+		if tree.Children == nil {
+			tree.Children = make(map[string]*ModuleTree)
+		}
+		tree.Children[child.name] = child.tree
+	}
+
+	return tree, diags
+}
+
+// Walk calls the given function for the receiver and then each of its
+// descendents, depth-first and in order of call name. If the function
+// returns an error then the walk stops and that error is returned.
+func (t *ModuleTree) Walk(fn func(*ModuleTree) error) error {
+	if err := fn(t); err != nil {
+		return err
+	}
+	for _, name := range SortedKeysOfMap(t.Children) {
+		if err := t.Children[name].Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddChildContents records the resources and outputs of each child module
+// in the tree in the ModuleCall of its parent that calls it, as
+// LoadIBMModule does, so that they are available to templates rendered
+// with RenderWithTemplate. Calls whose source could not be resolved are
+// left as they are.
+func (t *ModuleTree) AddChildContents() {
+	t.Walk(func(tree *ModuleTree) error {
+		for name, child := range tree.Children {
+			call := tree.Module.ModuleCalls[name]
+			if call == nil || child.Module == nil {
+				continue
+			}
+			if child.Module.ManagedResources != nil {
+				call.ManagedResources = child.Module.ManagedResources
+			}
+			if child.Module.DataResources != nil {
+				call.DataResources = child.Module.DataResources
+			}
+			if child.Module.Outputs != nil {
+				call.Outputs = child.Module.Outputs
+			}
+		}
+		return nil
+	})
+}
+
+func moduleAddress(path []string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	return "module." + strings.Join(path, ".module.")
+}
+
+// resolveModuleCallDir finds the directory containing the source of the
+// given module call, whose parent module is in parentDir.
+func resolveModuleCallDir(fs FS, rootDir string, manifest map[string]string, parentDir, key string, call *ModuleCall) (string, bool) {
+	if dir, ok := manifest[key]; ok {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(rootDir, dir)
+		}
+		return dir, IsModuleDirOnFilesystem(fs, dir)
+	}
+
+	if isLocalModuleSource(call.Source) {
+		dir := filepath.Join(parentDir, filepath.FromSlash(call.Source))
+		return dir, IsModuleDirOnFilesystem(fs, dir)
+	}
+
+	// Without a manifest we fall back on the directory naming convention
+	// used by "terraform init", as LoadIBMModule does.
+	dir := filepath.Join(rootDir, ".terraform", "modules", key)
+	if subdir := findSubModuleSourcePath(call.Source); subdir != "" {
+		dir = filepath.Join(dir, filepath.FromSlash(subdir))
+	}
+	return dir, IsModuleDirOnFilesystem(fs, dir)
+}
+
+func isLocalModuleSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") ||
+		strings.HasPrefix(source, ".\\") || strings.HasPrefix(source, "..\\")
+}
+
+// loadModuleManifest reads the .terraform/modules/modules.json file that
+// "terraform init" writes into the given root module directory, returning a
+// map from module keys such as "network.vpc" to the directory each module
+// was installed into. It returns nil if the manifest is absent or invalid.
+func loadModuleManifest(fs FS, rootDir string) map[string]string {
+	src, err := fs.ReadFile(filepath.Join(rootDir, ".terraform", "modules", "modules.json"))
+	if err != nil {
+		return nil
+	}
+	var manifest struct {
+		Modules []struct {
+			Key string `json:"Key"`
+			Dir string `json:"Dir"`
+		} `json:"Modules"`
+	}
+	if err := json.Unmarshal(src, &manifest); err != nil {
+		return nil
+	}
+	ret := make(map[string]string, len(manifest.Modules))
+	for _, m := range manifest.Modules {
+		if m.Key == "" {
+			continue // the root module
+		}
+		ret[m.Key] = filepath.FromSlash(m.Dir)
+	}
+	return ret
+}
@@ -0,0 +1,40 @@
+package tfconfig
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestLoadModuleTree(t *testing.T) {
+	root := filepath.Join("testdata", "module-tree")
+	tree, diags := LoadModuleTree(root)
+	if diags.HasErrors() {
+		t.Fatalf("unexpected errors: %s", diags.Err())
+	}
+
+	got := map[string]string{}
+	err := tree.Walk(func(t *ModuleTree) error {
+		got[t.Address] = t.Module.Path
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"":                             root,
+		"module.network":               filepath.Join(root, "network"),
+		"module.network.module.subnet": filepath.Join(root, "network", "subnet"),
+		"module.storage":               filepath.Join(root, ".terraform", "modules", "storage"),
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+
+	if len(diags) != 1 || diags[0].Summary != "Module not installed" {
+		t.Errorf("expected a single warning about module.missing, got %#v", diags)
+	}
+}
@@ -0,0 +1,59 @@
+package tfconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Moved represents a "moved" block, which records that an object in the
+// module's state has a new address.
+type Moved struct {
+	// From and To are the old and new addresses of the object, such as
+	// "aws_instance.web" or "module.network".
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	Pos SourcePos `json:"pos"`
+}
+
+// movedAddr returns the address given in the "from" or "to" argument of a
+// moved block, including any instance keys.
+func movedAddr(expr hcl.Expression) (string, hcl.Diagnostics) {
+	traversal, diags := hcl.AbsTraversalForExpr(expr)
+	if diags.HasErrors() {
+		return "", diags
+	}
+
+	var buf strings.Builder
+	for _, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			buf.WriteString(step.Name)
+		case hcl.TraverseAttr:
+			buf.WriteString("." + step.Name)
+		case hcl.TraverseIndex:
+			switch step.Key.Type() {
+			case cty.String:
+				fmt.Fprintf(&buf, "[%q]", step.Key.AsString())
+			case cty.Number:
+				fmt.Fprintf(&buf, "[%s]", step.Key.AsBigFloat().Text('f', -1))
+			}
+		}
+	}
+	return buf.String(), diags
+}
+
+// movedFromObject reports whether any of the given moved blocks moves the
+// object with the given address, such as "aws_instance.web", or one of its
+// instances.
+func movedFromObject(moved []*Moved, addr string) (*Moved, bool) {
+	for _, m := range moved {
+		if m.From == addr || strings.HasPrefix(m.From, addr+"[") {
+			return m, true
+		}
+	}
+	return nil, false
+}
@@ -11,4 +11,9 @@ type Output struct {
 	Source         []string      `json:"source,omitempty"`
 	CloudDataType  string        `json:"cloud_data_type,omitempty" description:"Cloud data type of the variable. eg. resource_group_id, region, vpc_id."`
 	CloudDataRange []interface{} `json:"cloud_data_range,omitempty" description:""`
+
+	// References are the addresses of the objects the output's value refers
+	// to, and DependsOn are those given in its depends_on argument.
+	References []string `json:"references,omitempty"`
+	DependsOn  []string `json:"depends_on,omitempty"`
 }
@@ -0,0 +1,176 @@
+package tfconfig
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// NewOverlayFS returns an FS that reads from the given base FS as if the
+// given edits had been saved to it, without changing it: each of the files
+// named in the given map has the given contents, whether or not it exists
+// in the base, and each of the given deleted files or directories does not
+// exist. This allows a module to be inspected with unsaved changes, such as
+// the buffers open in an editor.
+//
+// Paths use the separator of the operating system and are matched after
+// cleaning, so they must be given in the form that the paths passed to the
+// FS will take, such as "modules/app/main.tf" when loading "modules/app".
+// Directories that contain the files in the map exist even if the base has
+// no such directory, or it is deleted. Directory listings include the
+// files in the map and are sorted by name, as from disk, so that override
+// files are processed in the same order.
+func NewOverlayFS(base FS, files map[string][]byte, deleted []string) FS {
+	o := &overlayFs{
+		base:    base,
+		files:   make(map[string][]byte, len(files)),
+		dirs:    make(map[string]bool),
+		deleted: make(map[string]bool, len(deleted)),
+	}
+	for name, src := range files {
+		name = filepath.Clean(name)
+		o.files[name] = src
+		for dir := filepath.Dir(name); !o.dirs[dir]; dir = filepath.Dir(dir) {
+			o.dirs[dir] = true
+		}
+	}
+	for _, name := range deleted {
+		o.deleted[filepath.Clean(name)] = true
+	}
+	return o
+}
+
+type overlayFs struct {
+	base    FS
+	files   map[string][]byte
+	dirs    map[string]bool
+	deleted map[string]bool
+}
+
+func (o *overlayFs) Open(name string) (File, error) {
+	name = filepath.Clean(name)
+	if src, ok := o.files[name]; ok {
+		return overlayFileEntry(name, src).open()
+	}
+	if !o.dirs[name] {
+		if o.isDeleted(name) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		f, err := o.base.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		info, err := f.Stat()
+		if err != nil || !info.IsDir() {
+			return f, err
+		}
+		f.Close()
+	}
+
+	// Directories are listed afresh so that reading them through the
+	// returned file gives the same entries as ReadDir.
+	infos, err := o.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fileInfoDirEntry{info}
+	}
+	return &memDir{memEntry: o.dirEntry(name), entries: entries}, nil
+}
+
+func (o *overlayFs) ReadFile(name string) ([]byte, error) {
+	name = filepath.Clean(name)
+	if src, ok := o.files[name]; ok {
+		return append([]byte(nil), src...), nil
+	}
+	if o.dirs[name] {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	if o.isDeleted(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return o.base.ReadFile(name)
+}
+
+func (o *overlayFs) ReadDir(dirname string) ([]os.FileInfo, error) {
+	dirname = filepath.Clean(dirname)
+	if _, ok := o.files[dirname]; ok {
+		return nil, &fs.PathError{Op: "readdir", Path: dirname, Err: fs.ErrInvalid}
+	}
+
+	byName := make(map[string]os.FileInfo)
+	if !o.isDeleted(dirname) {
+		infos, err := o.base.ReadDir(dirname)
+		if err != nil && !o.dirs[dirname] {
+			return nil, err
+		}
+		for _, info := range infos {
+			if !o.deleted[filepath.Join(dirname, info.Name())] {
+				byName[info.Name()] = info
+			}
+		}
+	} else if !o.dirs[dirname] {
+		return nil, &fs.PathError{Op: "readdir", Path: dirname, Err: fs.ErrNotExist}
+	}
+
+	for name, src := range o.files {
+		if name != dirname && filepath.Dir(name) == dirname {
+			byName[filepath.Base(name)] = overlayFileEntry(name, src)
+		}
+	}
+	for name := range o.dirs {
+		if name != dirname && filepath.Dir(name) == dirname {
+			if info, ok := byName[filepath.Base(name)]; !ok || !info.IsDir() {
+				byName[filepath.Base(name)] = o.dirEntry(name)
+			}
+		}
+	}
+
+	infos := make([]os.FileInfo, 0, len(byName))
+	for _, info := range byName {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+	return infos, nil
+}
+
+// overlayFileEntry describes the file with the given path and contents.
+func overlayFileEntry(name string, src []byte) *memEntry {
+	return &memEntry{name: filepath.Base(name), size: int64(len(src)), data: src, mode: 0644}
+}
+
+// isDeleted reports whether the given cleaned path, or a directory
+// containing it, is deleted.
+func (o *overlayFs) isDeleted(name string) bool {
+	for {
+		if o.deleted[name] {
+			return true
+		}
+		parent := filepath.Dir(name)
+		if parent == name {
+			return false
+		}
+		name = parent
+	}
+}
+
+// dirEntry describes the given directory, with its details from the base
+// if it has it.
+func (o *overlayFs) dirEntry(name string) *memEntry {
+	e := &memEntry{name: filepath.Base(name), mode: fs.ModeDir | 0755}
+	if o.isDeleted(name) {
+		return e
+	}
+	if f, err := o.base.Open(name); err == nil {
+		if info, err := f.Stat(); err == nil && info.IsDir() {
+			e.mode, e.modTime = info.Mode(), info.ModTime()
+		}
+		f.Close()
+	}
+	return e
+}
@@ -0,0 +1,64 @@
+package tfconfig
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlayFS(t *testing.T) {
+	base := fstest.MapFS{
+		"mod/main.tf":              {Data: []byte("variable \"name\" {\n  description = \"base\"\n}\n")},
+		"mod/variables.tf":         {Data: []byte("variable \"removed\" {}\n")},
+		"mod/z_override.tf":        {Data: []byte("variable \"name\" {\n  description = \"z\"\n}\n")},
+		"mod/examples/basic/ex.tf": {Data: []byte("module \"example\" {\n  source = \"../..\"\n}\n")},
+	}
+	files := map[string][]byte{
+		filepath.Join("mod", "main.tf"):          []byte("variable \"name\" {\n  description = \"edited\"\n}\n\noutput \"name\" {\n  value = var.name\n}\n"),
+		filepath.Join("mod", "a_override.tf"):    []byte("variable \"name\" {\n  description = \"a\"\n}\n"),
+		filepath.Join("mod", "new", "new.tf"):    []byte("variable \"new\" {}\n"),
+		filepath.Join("mod", "examples", "x.tf"): []byte("variable \"x\" {}\n"),
+	}
+	deleted := []string{
+		filepath.Join("mod", "variables.tf"),
+		filepath.Join("mod", "examples"),
+	}
+
+	// The same edits, saved.
+	saved := fstest.MapFS{
+		"mod/z_override.tf": base["mod/z_override.tf"],
+	}
+	for name, src := range files {
+		saved[filepath.ToSlash(name)] = &fstest.MapFile{Data: src}
+	}
+
+	overlay := NewOverlayFS(FromIOFS(base), files, deleted)
+	if err := fstest.TestFS(ToIOFS(overlay), "mod/main.tf", "mod/a_override.tf", "mod/z_override.tf", "mod/new/new.tf", "mod/examples/x.tf"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{"mod", filepath.Join("mod", "new"), filepath.Join("mod", "examples")} {
+		got, _ := LoadModuleFromFilesystem(overlay, dir)
+		want, _ := LoadModuleFromFilesystem(FromIOFS(saved), dir)
+		gotJSON, _ := json.MarshalIndent(got, "", "  ")
+		wantJSON, _ := json.MarshalIndent(want, "", "  ")
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("wrong module in %s\ngot:  %s\nwant: %s", dir, gotJSON, wantJSON)
+		}
+	}
+
+	module, _ := LoadModuleFromFilesystem(overlay, "mod")
+	if got, want := module.Variables["name"].Description, "z"; got != want {
+		t.Errorf("wrong description %q; want %q from the last override file", got, want)
+	}
+	if _, ok := module.Variables["removed"]; ok {
+		t.Errorf("variable from deleted file was loaded")
+	}
+	if _, err := overlay.ReadFile(filepath.Join("mod", "examples", "basic", "ex.tf")); err == nil {
+		t.Errorf("file in deleted directory can be read")
+	}
+	if src, err := FromIOFS(base).ReadFile(filepath.Join("mod", "main.tf")); err != nil || string(src) != string(base["mod/main.tf"].Data) {
+		t.Errorf("base was changed: %q, %v", src, err)
+	}
+}
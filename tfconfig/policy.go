@@ -0,0 +1,510 @@
+package tfconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// PolicyRule is a Rule declared in a rule file, as loaded by LoadRuleFile.
+//
+// A rule file contains any number of "rule" blocks, each labelled with the
+// ID of the rule. Each rule contains one or more blocks selecting the
+// objects that it checks, which are "variable", "output", "resource",
+// "data", "module_call", "provider" or "module". Each of those blocks has a
+// "condition" expression that must be true for every selected object, an
+// optional "when" expression to select only some objects and an optional
+// "message" to report when the condition is false:
+//
+//	rule "instance_resource_group" {
+//	  description = "Instances must be placed in a resource group given by a variable."
+//	  severity    = "error"
+//
+//	  resource {
+//	    when      = self.type == "ibm_is_instance"
+//	    condition = try(module.variables[self.attributes.resource_group].cloud_data_type, "") == "resource_group"
+//	    message   = "${self.address} must set resource_group from a variable whose cloud data type is resource_group."
+//	  }
+//	}
+//
+// The expressions can refer to the object being checked as "self" and to
+// the module it belongs to as "module", and can use functions such as
+// "regex", "can", "try", "contains", "length", "lower" and "upper".
+type PolicyRule struct {
+	Name        string
+	Description string
+	Severity    DiagSeverity
+	Checks      []*PolicyCheck
+
+	Pos SourcePos
+}
+
+// PolicyCheck is a single block of a PolicyRule, checking one kind of
+// object.
+type PolicyCheck struct {
+	// Kind is the type of the block, such as "resource".
+	Kind string
+
+	When      hcl.Expression
+	Condition hcl.Expression
+	Message   hcl.Expression
+}
+
+// policyKinds are the kinds of object that a PolicyCheck can select.
+var policyKinds = []string{"variable", "output", "resource", "data", "module_call", "provider", "module"}
+
+var ruleFileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type:       "rule",
+			LabelNames: []string{"id"},
+		},
+	},
+}
+
+var ruleSchema = func() *hcl.BodySchema {
+	schema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{
+				Name: "description",
+			},
+			{
+				Name: "severity",
+			},
+		},
+	}
+	for _, kind := range policyKinds {
+		schema.Blocks = append(schema.Blocks, hcl.BlockHeaderSchema{Type: kind})
+	}
+	return schema
+}()
+
+var policyCheckSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{
+			Name: "when",
+		},
+		{
+			Name:     "condition",
+			Required: true,
+		},
+		{
+			Name: "message",
+		},
+	},
+}
+
+// LoadRuleFile loads the rules declared in the given file, in either native
+// HCL syntax or, for files whose names end in ".json", HCL's JSON syntax.
+func LoadRuleFile(filename string) ([]Rule, Diagnostics) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		diags := diagnosticsErrorf("Failed to read rule file: %s", err)
+		diags[0].Code = DiagCodeReadFile
+		return nil, diags
+	}
+	return ParseRules(src, filename)
+}
+
+// ParseRules parses the rules declared in the given rule file source,
+// treating it as HCL's JSON syntax if the filename ends in ".json".
+func ParseRules(src []byte, filename string) ([]Rule, Diagnostics) {
+	parser := hclparse.NewParser()
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if strings.HasSuffix(filename, ".json") {
+		file, diags = parser.ParseJSON(src, filename)
+	} else {
+		file, diags = parser.ParseHCL(src, filename)
+	}
+	if diags.HasErrors() {
+		return nil, diagnosticsHCL(diags)
+	}
+
+	content, contentDiags := file.Body.Content(ruleFileSchema)
+	diags = append(diags, contentDiags...)
+
+	var rules []Rule
+	seen := make(map[string]bool)
+	for _, block := range content.Blocks {
+		rule, ruleDiags := decodePolicyRule(block)
+		diags = append(diags, ruleDiags...)
+		if rule == nil {
+			continue
+		}
+		if seen[rule.Name] {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate rule",
+				Detail:   fmt.Sprintf("A rule with ID %q was already declared.", rule.Name),
+				Subject:  block.DefRange.Ptr(),
+				Extra:    diagCodeExtra(DiagCodeInvalidRuleFile),
+			})
+			continue
+		}
+		seen[rule.Name] = true
+		rules = append(rules, rule)
+	}
+	return rules, diagnosticsHCL(diags)
+}
+
+func decodePolicyRule(block *hcl.Block) (*PolicyRule, hcl.Diagnostics) {
+	content, diags := block.Body.Content(ruleSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	rule := &PolicyRule{
+		Name:     block.Labels[0],
+		Severity: DiagWarning,
+		Pos:      sourcePosHCL(block.DefRange),
+	}
+
+	if attr, defined := content.Attributes["description"]; defined {
+		diags = append(diags, gohcl.DecodeExpression(attr.Expr, nil, &rule.Description)...)
+	}
+
+	if attr, defined := content.Attributes["severity"]; defined {
+		var severity string
+		valDiags := gohcl.DecodeExpression(attr.Expr, nil, &severity)
+		diags = append(diags, valDiags...)
+		switch {
+		case valDiags.HasErrors():
+		case severity == "error":
+			rule.Severity = DiagError
+		case severity == "warning":
+			rule.Severity = DiagWarning
+		default:
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid rule severity",
+				Detail:   `The severity of a rule must be either "error" or "warning".`,
+				Subject:  attr.Expr.Range().Ptr(),
+				Extra:    diagCodeExtra(DiagCodeInvalidRuleFile),
+			})
+		}
+	}
+
+	for _, checkBlock := range content.Blocks {
+		checkContent, checkDiags := checkBlock.Body.Content(policyCheckSchema)
+		diags = append(diags, checkDiags...)
+		if checkDiags.HasErrors() {
+			continue
+		}
+		check := &PolicyCheck{
+			Kind:      checkBlock.Type,
+			Condition: checkContent.Attributes["condition"].Expr,
+		}
+		if attr, defined := checkContent.Attributes["when"]; defined {
+			check.When = attr.Expr
+		}
+		if attr, defined := checkContent.Attributes["message"]; defined {
+			check.Message = attr.Expr
+		}
+		rule.Checks = append(rule.Checks, check)
+	}
+
+	if len(rule.Checks) == 0 && !diags.HasErrors() {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Empty rule",
+			Detail:   fmt.Sprintf("Rule %q must contain at least one block selecting the objects it checks: %s.", rule.Name, strings.Join(policyKinds, ", ")),
+			Subject:  block.DefRange.Ptr(),
+			Extra:    diagCodeExtra(DiagCodeInvalidRuleFile),
+		})
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return rule, diags
+}
+
+// ID implements Rule.
+func (r *PolicyRule) ID() string {
+	return r.Name
+}
+
+// CheckModule implements Rule, treating the given module as a root module.
+func (r *PolicyRule) CheckModule(module *Module) Diagnostics {
+	return r.check("", module)
+}
+
+// CheckTree implements TreeRule, making the address of each module
+// available to the rule's expressions as "module.address".
+func (r *PolicyRule) CheckTree(tree *ModuleTree) Diagnostics {
+	var diags Diagnostics
+	tree.Walk(func(t *ModuleTree) error {
+		diags = append(diags, r.check(t.Address, t.Module)...)
+		return nil
+	})
+	return diags
+}
+
+func (r *PolicyRule) check(address string, module *Module) Diagnostics {
+	moduleVal := policyModuleValue(address, module)
+
+	var diags Diagnostics
+	for _, check := range r.Checks {
+		for _, obj := range policyObjects(check.Kind, module, moduleVal) {
+			if diag := r.checkObject(check, obj, moduleVal); diag != nil {
+				diags = append(diags, *diag)
+			}
+		}
+	}
+	return diags
+}
+
+// checkObject evaluates the given check against a single object, returning
+// a diagnostic if the check fails or cannot be evaluated.
+func (r *PolicyRule) checkObject(check *PolicyCheck, obj policyObject, moduleVal cty.Value) *Diagnostic {
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"self":   obj.Value,
+			"module": moduleVal,
+		},
+		Functions: policyFunctions,
+	}
+
+	invalid := func(what string, diags hcl.Diagnostics) *Diagnostic {
+		return &Diagnostic{
+			Severity: DiagError,
+			Summary:  "Invalid rule " + what,
+			Detail:   fmt.Sprintf("Failed to evaluate the %s of rule %q for %s: %s", what, r.Name, obj.Address, diags.Error()),
+			Pos:      &r.Pos,
+		}
+	}
+
+	if check.When != nil {
+		var when bool
+		if diags := gohcl.DecodeExpression(check.When, ctx, &when); diags.HasErrors() {
+			return invalid("when expression", diags)
+		}
+		if !when {
+			return nil
+		}
+	}
+
+	var ok bool
+	if diags := gohcl.DecodeExpression(check.Condition, ctx, &ok); diags.HasErrors() {
+		return invalid("condition", diags)
+	}
+	if ok {
+		return nil
+	}
+
+	summary := r.Description
+	if summary == "" {
+		summary = fmt.Sprintf("Rule %q failed", r.Name)
+	}
+	detail := fmt.Sprintf("%s does not satisfy rule %q.", obj.Address, r.Name)
+	if check.Message != nil {
+		if diags := gohcl.DecodeExpression(check.Message, ctx, &detail); diags.HasErrors() {
+			return invalid("message", diags)
+		}
+	}
+	return &Diagnostic{
+		Severity: r.Severity,
+		Summary:  summary,
+		Detail:   detail,
+		Pos:      obj.Pos,
+	}
+}
+
+var policyFunctions = map[string]function.Function{
+	"can":       tryfunc.CanFunc,
+	"try":       tryfunc.TryFunc,
+	"regex":     stdlib.RegexFunc,
+	"regexall":  stdlib.RegexAllFunc,
+	"contains":  stdlib.ContainsFunc,
+	"length":    policyLengthFunc,
+	"lower":     stdlib.LowerFunc,
+	"upper":     stdlib.UpperFunc,
+	"keys":      stdlib.KeysFunc,
+	"values":    stdlib.ValuesFunc,
+	"lookup":    stdlib.LookupFunc,
+	"join":      stdlib.JoinFunc,
+	"split":     stdlib.SplitFunc,
+	"substr":    stdlib.SubstrFunc,
+	"coalesce":  stdlib.CoalesceFunc,
+	"tostring":  stdlib.MakeToFunc(cty.String),
+	"tonumber":  stdlib.MakeToFunc(cty.Number),
+	"tobool":    stdlib.MakeToFunc(cty.Bool),
+	"trimspace": stdlib.TrimSpaceFunc,
+}
+
+// policyLengthFunc is like stdlib.LengthFunc but also accepts objects, since
+// the collections of a module are represented as objects keyed by name.
+var policyLengthFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "collection",
+			Type:             cty.DynamicPseudoType,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		if ty := args[0].Type(); ty.IsObjectType() {
+			return cty.NumberIntVal(int64(len(ty.AttributeTypes()))), nil
+		}
+		return stdlib.Length(args[0])
+	},
+})
+
+// policyObject is an object that a PolicyCheck selected, with its value as
+// seen by the check's expressions.
+type policyObject struct {
+	Address string
+	Value   cty.Value
+	Pos     *SourcePos
+}
+
+// policyObjects returns the objects of the given kind in the given module,
+// in order of address.
+func policyObjects(kind string, module *Module, moduleVal cty.Value) []policyObject {
+	var ret []policyObject
+	switch kind {
+	case "variable":
+		for _, name := range SortedKeysOfMap(module.Variables) {
+			ret = append(ret, policyObject{"var." + name, moduleVal.GetAttr("variables").GetAttr(name), module.Variables[name].Pos})
+		}
+	case "output":
+		for _, name := range SortedKeysOfMap(module.Outputs) {
+			ret = append(ret, policyObject{"output." + name, moduleVal.GetAttr("outputs").GetAttr(name), module.Outputs[name].Pos})
+		}
+	case "resource", "data":
+		resources, attr := module.ManagedResources, "resources"
+		if kind == "data" {
+			resources, attr = module.DataResources, "data_resources"
+		}
+		for _, key := range SortedKeysOfMap(resources) {
+			pos := resources[key].Pos
+			ret = append(ret, policyObject{key, moduleVal.GetAttr(attr).GetAttr(key), &pos})
+		}
+	case "module_call":
+		for _, name := range SortedKeysOfMap(module.ModuleCalls) {
+			pos := module.ModuleCalls[name].Pos
+			ret = append(ret, policyObject{"module." + name, moduleVal.GetAttr("module_calls").GetAttr(name), &pos})
+		}
+	case "provider":
+		for _, name := range SortedKeysOfMap(module.RequiredProviders) {
+			ret = append(ret, policyObject{"provider." + name, moduleVal.GetAttr("providers").GetAttr(name), providerUsePos(module, name)})
+		}
+	case "module":
+		address := moduleVal.GetAttr("address").AsString()
+		if address == "" {
+			address = "The root module"
+		}
+		ret = append(ret, policyObject{address, moduleVal, nil})
+	}
+	return ret
+}
+
+// policyModuleValue returns the value of the given module as seen by the
+// expressions of a PolicyRule.
+func policyModuleValue(address string, module *Module) cty.Value {
+	variables := make(map[string]cty.Value)
+	for name, v := range module.Variables {
+		def, err := ctyValueFromGo(v.Default)
+		if err != nil || v.Default == nil {
+			def = cty.NullVal(cty.DynamicPseudoType)
+		}
+		variables[name] = cty.ObjectVal(map[string]cty.Value{
+			"name":            cty.StringVal(name),
+			"address":         cty.StringVal("var." + name),
+			"type":            cty.StringVal(variableTypeString(v)),
+			"description":     cty.StringVal(v.Description),
+			"default":         def,
+			"required":        cty.BoolVal(isRequired(v)),
+			"sensitive":       cty.BoolVal(isTrue(v.Sensitive)),
+			"cloud_data_type": cty.StringVal(v.CloudDataType),
+		})
+	}
+
+	outputs := make(map[string]cty.Value)
+	for name, o := range module.Outputs {
+		outputs[name] = cty.ObjectVal(map[string]cty.Value{
+			"name":        cty.StringVal(name),
+			"address":     cty.StringVal("output." + name),
+			"description": cty.StringVal(o.Description),
+			"value":       cty.StringVal(o.Value),
+			"sensitive":   cty.BoolVal(o.Sensitive),
+			"references":  policyStringList(o.References),
+		})
+	}
+
+	resourcesValue := func(resources map[string]*Resource) cty.Value {
+		ret := make(map[string]cty.Value)
+		for key, r := range resources {
+			attrs := make(map[string]cty.Value)
+			for name, v := range r.Attributes {
+				if s, ok := v.(string); ok {
+					attrs[name] = cty.StringVal(s)
+				}
+			}
+			ret[key] = cty.ObjectVal(map[string]cty.Value{
+				"mode":       cty.StringVal(r.Mode.String()),
+				"type":       cty.StringVal(r.Type),
+				"name":       cty.StringVal(r.Name),
+				"address":    cty.StringVal(key),
+				"provider":   cty.StringVal(r.Provider.Name),
+				"attributes": cty.ObjectVal(attrs),
+				"references": policyStringList(r.References),
+				"depends_on": policyStringList(r.DependsOn),
+			})
+		}
+		return cty.ObjectVal(ret)
+	}
+
+	calls := make(map[string]cty.Value)
+	for name, mc := range module.ModuleCalls {
+		calls[name] = cty.ObjectVal(map[string]cty.Value{
+			"name":       cty.StringVal(name),
+			"address":    cty.StringVal("module." + name),
+			"source":     cty.StringVal(mc.Source),
+			"version":    cty.StringVal(mc.Version),
+			"references": policyStringList(mc.References),
+		})
+	}
+
+	providers := make(map[string]cty.Value)
+	for name, p := range module.RequiredProviders {
+		providers[name] = cty.ObjectVal(map[string]cty.Value{
+			"name":                cty.StringVal(name),
+			"address":             cty.StringVal("provider." + name),
+			"source":              cty.StringVal(p.Source),
+			"version_constraints": policyStringList(p.VersionConstraints),
+		})
+	}
+
+	return cty.ObjectVal(map[string]cty.Value{
+		"address":        cty.StringVal(address),
+		"path":           cty.StringVal(module.Path),
+		"required_core":  policyStringList(module.RequiredCore),
+		"variables":      cty.ObjectVal(variables),
+		"outputs":        cty.ObjectVal(outputs),
+		"resources":      resourcesValue(module.ManagedResources),
+		"data_resources": resourcesValue(module.DataResources),
+		"module_calls":   cty.ObjectVal(calls),
+		"providers":      cty.ObjectVal(providers),
+	})
+}
+
+func policyStringList(strs []string) cty.Value {
+	if len(strs) == 0 {
+		return cty.ListValEmpty(cty.String)
+	}
+	vals := make([]cty.Value, len(strs))
+	for i, s := range strs {
+		vals[i] = cty.StringVal(s)
+	}
+	return cty.ListVal(vals)
+}
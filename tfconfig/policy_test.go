@@ -0,0 +1,164 @@
+package tfconfig
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestPolicyRules(t *testing.T) {
+	path := filepath.Join("testdata", "policy")
+	// The cloud data types of variables are added from provider metadata.
+	module, diags := CheckForInitDirectoryAndLoadIBMModule(path, filepath.Join(path, "policy.metadata.json"))
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object: %s", diags)
+	}
+	if got := module.Variables["resource_group"].CloudDataType; got != "resource_group" {
+		t.Fatalf("wrong cloud data type %q from metadata", got)
+	}
+
+	rules, diags := LoadRuleFile(filepath.Join(path, "policy.rules.hcl"))
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	filename := filepath.Join(path, "policy.tf")
+	want := Diagnostics{
+		{
+			Severity: DiagError,
+			Summary:  "Instances must be placed in a resource group given by a variable.",
+			Detail:   "ibm_is_instance.bad must set resource_group from a variable whose cloud data type is resource_group.",
+			Pos:      &SourcePos{Filename: filename, Line: 16},
+			Code:     "instance_resource_group",
+			Rule:     "instance_resource_group",
+		},
+		{
+			Severity: DiagError,
+			Summary:  "Instances must be placed in a resource group given by a variable.",
+			Detail:   "ibm_is_instance.missing must set resource_group from a variable whose cloud data type is resource_group.",
+			Pos:      &SourcePos{Filename: filename, Line: 21},
+			Code:     "instance_resource_group",
+			Rule:     "instance_resource_group",
+		},
+		{
+			Severity: DiagWarning,
+			Summary:  "Modules must be sourced from our GitHub organization.",
+			Detail:   `module.external does not satisfy rule "module_source_org".`,
+			Pos:      &SourcePos{Filename: filename, Line: 33},
+			Code:     "module_source_org",
+			Rule:     "module_source_org",
+		},
+	}
+
+	got := Lint(module, rules)
+	if diff := deep.Equal(got, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
+
+func TestPolicyRulesTree(t *testing.T) {
+	tree, diags := LoadModuleTree(filepath.Join("testdata", "module-tree"))
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	rules, diags := ParseRules([]byte(`
+rule "child_module_resources" {
+  module {
+    when      = module.address != ""
+    condition = length(module.resources) > 0
+    message   = "${module.address} declares no resources."
+  }
+}
+`), "rules.hcl")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	var got []string
+	for _, diag := range LintTree(tree, rules) {
+		got = append(got, diag.Detail)
+	}
+	want := []string{"module.storage declares no resources."}
+	if diff := deep.Equal(got, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := map[string]string{
+		"Invalid rule severity": `
+rule "a" {
+  severity = "fatal"
+  variable {
+    condition = true
+  }
+}
+`,
+		"Empty rule": `
+rule "a" {
+  description = "Does nothing"
+}
+`,
+		"Duplicate rule": `
+rule "a" {
+  variable {
+    condition = true
+  }
+}
+rule "a" {
+  output {
+    condition = true
+  }
+}
+`,
+		"Missing required argument": `
+rule "a" {
+  variable {
+    when = true
+  }
+}
+`,
+	}
+
+	for want, src := range tests {
+		t.Run(want, func(t *testing.T) {
+			_, diags := ParseRules([]byte(src), "rules.hcl")
+			if !diags.HasErrors() {
+				t.Fatalf("no errors")
+			}
+			if got := diags[0].Summary; got != want {
+				t.Errorf("wrong error %q; want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPolicyRuleInvalidCondition(t *testing.T) {
+	module, _ := LoadModule(filepath.Join("testdata", "policy"))
+	rules, diags := ParseRules([]byte(`
+rule "bad" {
+  variable {
+    condition = self.no_such_attribute
+  }
+}
+`), "rules.hcl")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	got := Lint(module, rules)
+	if len(got) != len(module.Variables) {
+		t.Fatalf("got %d diagnostics; want %d", len(got), len(module.Variables))
+	}
+	for _, diag := range got {
+		if diag.Severity != DiagError || diag.Summary != "Invalid rule condition" || diag.Rule != "bad" {
+			t.Errorf("unexpected diagnostic %#v", diag)
+		}
+	}
+}
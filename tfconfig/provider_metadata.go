@@ -0,0 +1,74 @@
+package tfconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ProviderMetadata is the provider metadata that LoadIBMModule uses to
+// enrich the variables of a module, describing the arguments of each
+// resource and data source type under the keys "Resources" and
+// "Datasources".
+//
+// It can be built directly, or parsed from JSON with ParseProviderMetadata,
+// ReadProviderMetadata or LoadProviderMetadataFile.
+type ProviderMetadata map[string]interface{}
+
+// ParseProviderMetadata parses the given provider metadata JSON.
+func ParseProviderMetadata(src []byte) (ProviderMetadata, Diagnostics) {
+	return parseProviderMetadata(src, "")
+}
+
+// ReadProviderMetadata reads provider metadata JSON from the given reader.
+func ReadProviderMetadata(r io.Reader) (ProviderMetadata, Diagnostics) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, Diagnostics{
+			{
+				Severity: DiagError,
+				Summary:  "Failed to read metadata file",
+				Code:     DiagCodeMetadata,
+				Detail:   fmt.Sprintf("Failed to read metadata: %s", err),
+			},
+		}
+	}
+	return parseProviderMetadata(src, "")
+}
+
+// LoadProviderMetadataFile reads the provider metadata JSON file at the
+// given path in the given FS.
+func LoadProviderMetadataFile(fs FS, filename string) (ProviderMetadata, Diagnostics) {
+	src, err := fs.ReadFile(filename)
+	if err != nil {
+		return nil, Diagnostics{
+			{
+				Severity: DiagError,
+				Summary:  "Failed to read metadata file",
+				Code:     DiagCodeMetadata,
+				Detail:   fmt.Sprintf("Failed to read metadataPath file %s %s", filename, err),
+			},
+		}
+	}
+	return parseProviderMetadata(src, filename)
+}
+
+func parseProviderMetadata(src []byte, filename string) (ProviderMetadata, Diagnostics) {
+	var metadata ProviderMetadata
+	if err := json.Unmarshal(src, &metadata); err != nil {
+		detail := fmt.Sprintf("Failed to unmarshal metadata json: %s", err)
+		if filename != "" {
+			detail = fmt.Sprintf("Failed to unmarshal metadata json %s: %s", filename, err)
+		}
+		return nil, Diagnostics{
+			{
+				Severity: DiagError,
+				Summary:  "Invalid metadata file",
+				Code:     DiagCodeMetadata,
+				Detail:   detail,
+			},
+		}
+	}
+	return metadata, nil
+}
@@ -46,6 +46,7 @@ func decodeRequiredProvidersBlock(block *hcl.Block) (map[string]*ProviderRequire
 				Summary:  "Invalid required_providers object",
 				Detail:   "Required providers entries must be strings or objects.",
 				Subject:  attr.Expr.Range().Ptr(),
+				Extra:    diagCodeExtra(DiagCodeRequiredProviders),
 			})
 			continue
 		}
@@ -65,6 +66,7 @@ func decodeRequiredProvidersBlock(block *hcl.Block) (map[string]*ProviderRequire
 					Summary:  "Invalid Attribute",
 					Detail:   fmt.Sprintf("Invalid attribute value for provider requirement: %#v", key),
 					Subject:  kv.Key.Range().Ptr(),
+					Extra:    diagCodeExtra(DiagCodeRequiredProviders),
 				})
 				continue
 			}
@@ -78,6 +80,7 @@ func decodeRequiredProvidersBlock(block *hcl.Block) (map[string]*ProviderRequire
 						Summary:  "Unsuitable value type",
 						Detail:   "Unsuitable value: string required",
 						Subject:  attr.Expr.Range().Ptr(),
+						Extra:    diagCodeExtra(DiagCodeRequiredProviders),
 					})
 					continue
 				}
@@ -93,6 +96,7 @@ func decodeRequiredProvidersBlock(block *hcl.Block) (map[string]*ProviderRequire
 						Summary:  "Unsuitable value type",
 						Detail:   "Unsuitable value: string required",
 						Subject:  attr.Expr.Range().Ptr(),
+						Extra:    diagCodeExtra(DiagCodeRequiredProviders),
 					})
 					continue
 				}
@@ -140,6 +144,7 @@ func decodeConfigurationAliases(localName string, value hcl.Expression) ([]Provi
 				Summary:  "Invalid configuration_aliases value",
 				Detail:   `Configuration aliases can only contain references to local provider configuration names in the format of provider.alias`,
 				Subject:  value.Range().Ptr(),
+				Extra:    diagCodeExtra(DiagCodeRequiredProviders),
 			})
 			continue
 		}
@@ -150,6 +155,7 @@ func decodeConfigurationAliases(localName string, value hcl.Expression) ([]Provi
 				Summary:  "Invalid configuration_aliases value",
 				Detail:   fmt.Sprintf(`Configuration aliases must be prefixed with the provider name. Expected %q, but found %q.`, localName, ref.Name),
 				Subject:  value.Range().Ptr(),
+				Extra:    diagCodeExtra(DiagCodeRequiredProviders),
 			})
 			continue
 		}
@@ -182,6 +188,7 @@ func parseProviderRef(traversal hcl.Traversal) (ProviderRef, hcl.Diagnostics) {
 			Summary:  "Invalid provider configuration address",
 			Detail:   "The provider type name must either stand alone or be followed by an alias name separated with a dot.",
 			Subject:  aliasStep.SourceRange().Ptr(),
+			Extra:    diagCodeExtra(DiagCodeProviderReference),
 		})
 	}
 
@@ -191,6 +198,7 @@ func parseProviderRef(traversal hcl.Traversal) (ProviderRef, hcl.Diagnostics) {
 			Summary:  "Invalid provider configuration address",
 			Detail:   "Extraneous extra operators after provider configuration address.",
 			Subject:  traversal[2:].SourceRange().Ptr(),
+			Extra:    diagCodeExtra(DiagCodeProviderReference),
 		})
 	}
 
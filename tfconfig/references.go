@@ -0,0 +1,153 @@
+package tfconfig
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// referenceAddr returns the address of the object a traversal refers to,
+// such as "var.name", "local.name", "aws_instance.web",
+// "data.aws_ami.ubuntu" or "module.vpc.subnet_id", or an empty string if
+// the traversal doesn't refer to an object in the module.
+func referenceAddr(traversal hcl.Traversal) string {
+	if len(traversal) < 2 {
+		return ""
+	}
+	root := traversal.RootName()
+	attrName := func(i int) string {
+		if i >= len(traversal) {
+			return ""
+		}
+		if attr, ok := traversal[i].(hcl.TraverseAttr); ok {
+			return attr.Name
+		}
+		return ""
+	}
+
+	switch root {
+	case "var", "local":
+		if name := attrName(1); name != "" {
+			return root + "." + name
+		}
+	case "data":
+		typeName, name := attrName(1), attrName(2)
+		if typeName != "" && name != "" {
+			return "data." + typeName + "." + name
+		}
+	case "module":
+		name := attrName(1)
+		if name == "" {
+			return ""
+		}
+		// Module outputs may be accessed either directly or after indexing
+		// a module call that uses count or for_each.
+		for i := 2; i < len(traversal) && i < 4; i++ {
+			if output := attrName(i); output != "" {
+				return "module." + name + "." + output
+			}
+		}
+		return "module." + name
+	case "path", "terraform", "count", "each", "self":
+		// These are not references to other objects.
+	default:
+		if name := attrName(1); name != "" {
+			return root + "." + name
+		}
+	}
+	return ""
+}
+
+// referencesForExpr returns the sorted, unique addresses of the objects
+// referred to by the given expression.
+func referencesForExpr(expr hcl.Expression) []string {
+	return referencesForTraversals(expr.Variables())
+}
+
+// referencesForBody returns the sorted, unique addresses of the objects
+// referred to anywhere in the given body, including in nested blocks but
+// excluding the named top-level arguments.
+func referencesForBody(body hcl.Body, exclude ...string) []string {
+	skip := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		skip[name] = true
+	}
+	return referencesForTraversals(traversalsForBody(body, skip))
+}
+
+// lifecycleSkip are the arguments of lifecycle blocks that don't refer to
+// other objects.
+var lifecycleSkip = map[string]bool{
+	"ignore_changes":       true,
+	"replace_triggered_by": true,
+}
+
+func traversalsForBody(body hcl.Body, skip map[string]bool) []hcl.Traversal {
+	var traversals []hcl.Traversal
+	if synBody, ok := body.(*hclsyntax.Body); ok {
+		for name, attr := range synBody.Attributes {
+			if !skip[name] {
+				traversals = append(traversals, attr.Expr.Variables()...)
+			}
+		}
+		for _, block := range synBody.Blocks {
+			if block.Type == "lifecycle" {
+				// The conditions of preconditions and postconditions may
+				// refer to other objects, but these arguments only name
+				// attributes of the object itself, or trigger replacement.
+				traversals = append(traversals, traversalsForBody(block.Body, lifecycleSkip)...)
+				continue
+			}
+			traversals = append(traversals, traversalsForBody(block.Body, nil)...)
+		}
+	} else {
+		// Other syntaxes, namely JSON, can't distinguish attributes from
+		// nested blocks without a schema, so we treat everything as an
+		// attribute. The arguments of lifecycle blocks that don't refer to
+		// other objects are plain strings, which have no references.
+		attrs, _ := body.JustAttributes()
+		for name, attr := range attrs {
+			if !skip[name] {
+				traversals = append(traversals, attr.Expr.Variables()...)
+			}
+		}
+	}
+	return traversals
+}
+
+// dependsOnForExpr returns the addresses listed in the given depends_on
+// argument expression.
+func dependsOnForExpr(expr hcl.Expression) ([]string, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	exprs, listDiags := hcl.ExprList(expr)
+	diags = append(diags, listDiags...)
+
+	var ret []string
+	for _, expr := range exprs {
+		traversal, travDiags := hcl.AbsTraversalForExpr(expr)
+		diags = append(diags, travDiags...)
+		if travDiags.HasErrors() {
+			continue
+		}
+		if addr := referenceAddr(traversal); addr != "" {
+			ret = append(ret, addr)
+		}
+	}
+	return ret, diags
+}
+
+func referencesForTraversals(traversals []hcl.Traversal) []string {
+	seen := make(map[string]bool)
+	var ret []string
+	for _, traversal := range traversals {
+		addr := referenceAddr(traversal)
+		if addr == "" || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		ret = append(ret, addr)
+	}
+	sort.Strings(ret)
+	return ret
+}
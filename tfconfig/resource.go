@@ -13,6 +13,11 @@ type Resource struct {
 	Name       string                 `json:"name"`
 	Attributes map[string]interface{} `json:"attributes,omitempty"`
 
+	// References are the addresses of the objects the resource's arguments
+	// refer to, and DependsOn are those given in its depends_on argument.
+	References []string `json:"references,omitempty"`
+	DependsOn  []string `json:"depends_on,omitempty"`
+
 	Provider ProviderRef `json:"provider"`
 
 	Pos SourcePos `json:"pos"`
@@ -57,6 +62,25 @@ func (m ResourceMode) MarshalJSON() ([]byte, error) {
 	return []byte(strconv.Quote(m.String())), nil
 }
 
+// UnmarshalJSON implements encoding/json.Unmarshaler.
+func (m *ResourceMode) UnmarshalJSON(src []byte) error {
+	s, err := strconv.Unquote(string(src))
+	if err != nil {
+		return err
+	}
+	switch s {
+	case "managed":
+		*m = ManagedResourceMode
+	case "data":
+		*m = DataResourceMode
+	case "":
+		*m = InvalidResourceMode
+	default:
+		return fmt.Errorf("invalid resource mode %q", s)
+	}
+	return nil
+}
+
 func resourceTypeDefaultProviderName(typeName string) string {
 	if underPos := strings.IndexByte(typeName, '_'); underPos != -1 {
 		return typeName[:underPos]
@@ -0,0 +1,102 @@
+package tfconfig
+
+import (
+	"fmt"
+)
+
+// Rule is a check over a module, such as the built-in LintRules or the
+// organization policies loaded by LoadRuleFile.
+type Rule interface {
+	// ID identifies the rule, for enabling or disabling it. It is recorded
+	// in the Rule field of each diagnostic the rule returns.
+	ID() string
+
+	// CheckModule returns a diagnostic for each problem the rule finds in
+	// the given module.
+	CheckModule(module *Module) Diagnostics
+}
+
+// TreeRule is implemented by rules that check a whole module tree at once,
+// for example to take into account the address of each module or how
+// modules relate to each other. LintTree calls CheckTree rather than
+// CheckModule for such rules.
+type TreeRule interface {
+	Rule
+
+	CheckTree(tree *ModuleTree) Diagnostics
+}
+
+// SelectRules returns the given rules whose IDs are in enable, or all of
+// them if enable is empty, minus those whose IDs are in disable. It returns
+// an error if any of the IDs are not those of the given rules.
+func SelectRules(rules []Rule, enable, disable []string) ([]Rule, error) {
+	known := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		known[rule.ID()] = true
+	}
+	for _, id := range append(append([]string(nil), enable...), disable...) {
+		if !known[id] {
+			return nil, fmt.Errorf("unknown rule %q", id)
+		}
+	}
+
+	enabled := make(map[string]bool)
+	for _, id := range enable {
+		enabled[id] = true
+	}
+	disabled := make(map[string]bool)
+	for _, id := range disable {
+		disabled[id] = true
+	}
+
+	var ret []Rule
+	for _, rule := range rules {
+		if (len(enable) == 0 || enabled[rule.ID()]) && !disabled[rule.ID()] {
+			ret = append(ret, rule)
+		}
+	}
+	return ret, nil
+}
+
+// Lint runs the given rules over the given module, returning the problems
+// they find in the order of the rules.
+func Lint(module *Module, rules []Rule) Diagnostics {
+	var diags Diagnostics
+	for _, rule := range rules {
+		diags = append(diags, ruleDiagnostics(rule, rule.CheckModule(module))...)
+	}
+	return diags
+}
+
+// LintTree runs the given rules over every module in the given tree,
+// returning the problems they find in the order of the rules and then of
+// the modules, as visited by Walk.
+func LintTree(tree *ModuleTree, rules []Rule) Diagnostics {
+	var diags Diagnostics
+	for _, rule := range rules {
+		if treeRule, ok := rule.(TreeRule); ok {
+			diags = append(diags, ruleDiagnostics(rule, treeRule.CheckTree(tree))...)
+			continue
+		}
+		tree.Walk(func(t *ModuleTree) error {
+			diags = append(diags, ruleDiagnostics(rule, rule.CheckModule(t.Module))...)
+			return nil
+		})
+	}
+	return diags
+}
+
+// ruleDiagnostics records the ID of the given rule in each of the given
+// diagnostics that doesn't already have one, and uses it as the code of
+// those that don't have a code.
+func ruleDiagnostics(rule Rule, diags Diagnostics) Diagnostics {
+	for i := range diags {
+		if diags[i].Rule == "" {
+			diags[i].Rule = rule.ID()
+		}
+		if diags[i].Code == "" {
+			diags[i].Code = rule.ID()
+		}
+	}
+	return diags
+}
@@ -0,0 +1,171 @@
+package tfconfig
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SARIFVersion is the version of the SARIF format written by RenderSARIF.
+const SARIFVersion = "2.1.0"
+
+// SARIFSchema is the URI of the JSON Schema for the SARIF format written by
+// RenderSARIF.
+const SARIFSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// SARIFDefaultRuleID is the rule ID given to diagnostics that weren't
+// produced by a rule, such as those found while loading a module.
+const SARIFDefaultRuleID = "config"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// RenderSARIF writes the given diagnostics as a SARIF log with a single
+// run, as understood by code scanning tools such as GitHub's.
+//
+// Each diagnostic becomes one result, identified by the ID of the rule that
+// produced it or SARIFDefaultRuleID. Source file paths are made relative to
+// the given root directory, usually the directory that was scanned, and
+// are marked as relative to the %SRCROOT% base. Diagnostics with a Range
+// are given a full region, including columns; others only a start line.
+func RenderSARIF(w io.Writer, diags Diagnostics, rootDir string) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "terraform-config-inspect",
+				InformationURI: "https://github.com/IBM-Cloud/terraform-config-inspect",
+				Rules:          []sarifRule{},
+			},
+		},
+		Results: []sarifResult{},
+	}
+
+	ruleIDs := make(map[string]bool)
+	for _, diag := range diags {
+		result := sarifResult{
+			RuleID:  diag.Rule,
+			Level:   "warning",
+			Message: sarifMessage{Text: diagnosticMessage(diag)},
+		}
+		if result.RuleID == "" {
+			result.RuleID = SARIFDefaultRuleID
+		}
+		ruleIDs[result.RuleID] = true
+		if diag.Severity == DiagError {
+			result.Level = "error"
+		}
+		if diag.Pos != nil && diag.Pos.Filename != "" {
+			loc := sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{
+					URI:       relativeSlashPath(rootDir, diag.Pos.Filename),
+					URIBaseID: "%SRCROOT%",
+				},
+			}
+			if rng := diag.Range; rng != nil && rng.Start.Line > 0 {
+				// SARIF columns count characters from one and its end
+				// column is exclusive, which matches HCL closely enough.
+				loc.Region = &sarifRegion{
+					StartLine:   rng.Start.Line,
+					StartColumn: rng.Start.Column,
+					EndLine:     rng.End.Line,
+					EndColumn:   rng.End.Column,
+				}
+			} else if diag.Pos.Line > 0 {
+				loc.Region = &sarifRegion{StartLine: diag.Pos.Line}
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: loc}}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	for id := range ruleIDs {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id})
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  SARIFSchema,
+		Version: SARIFVersion,
+		Runs:    []sarifRun{run},
+	})
+}
+
+// diagnosticMessage returns the summary and detail of the given diagnostic
+// as a single message.
+func diagnosticMessage(diag Diagnostic) string {
+	if diag.Detail == "" {
+		return diag.Summary
+	}
+	return strings.TrimSuffix(diag.Summary, ".") + ": " + diag.Detail
+}
+
+// relativeSlashPath returns the given path relative to the given root
+// directory and with forward slashes, or just with forward slashes if it
+// is not within the root directory.
+func relativeSlashPath(rootDir, path string) string {
+	if rel, err := filepath.Rel(rootDir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,108 @@
+package tfconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestRenderSARIF(t *testing.T) {
+	path := filepath.Join("testdata", "lint")
+	module, diags := LoadModule(path)
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+	diags = append(diags, Lint(module, LintRules)...)
+
+	expected, err := ioutil.ReadFile(filepath.Join(path, "lint.out.sarif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderSARIF(&buf, diags, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(buf.String(), string(expected)); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
+
+func TestRenderSARIFEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderSARIF(&buf, nil, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	run := got["runs"].([]interface{})[0].(map[string]interface{})
+	if results := run["results"].([]interface{}); len(results) != 0 {
+		t.Errorf("unexpected results %#v", results)
+	}
+	if got["version"] != SARIFVersion {
+		t.Errorf("wrong version %q", got["version"])
+	}
+}
+
+func TestRenderSARIFRegion(t *testing.T) {
+	diags := Diagnostics{
+		{
+			Severity: DiagError,
+			Summary:  "Invalid expression",
+			Pos:      &SourcePos{Filename: "main.tf", Line: 3},
+			Range: &SourceRange{
+				Filename: "main.tf",
+				Start:    SourcePoint{Line: 3, Column: 11, Byte: 40},
+				End:      SourcePoint{Line: 4, Column: 2, Byte: 52},
+			},
+		},
+		{
+			Severity: DiagWarning,
+			Summary:  "Deprecated block",
+			Pos:      &SourcePos{Filename: "main.tf", Line: 7},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderSARIF(&buf, diags, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Runs []struct {
+			Results []struct {
+				Locations []struct {
+					PhysicalLocation struct {
+						Region map[string]int `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	var regions []map[string]int
+	for _, result := range got.Runs[0].Results {
+		regions = append(regions, result.Locations[0].PhysicalLocation.Region)
+	}
+	want := []map[string]int{
+		{"startLine": 3, "startColumn": 11, "endLine": 4, "endColumn": 2},
+		{"startLine": 7},
+	}
+	if diff := deep.Equal(regions, want); diff != nil {
+		for _, problem := range diff {
+			t.Errorf("%s", problem)
+		}
+	}
+}
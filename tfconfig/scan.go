@@ -0,0 +1,306 @@
+package tfconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultScanExclude are the glob patterns of the directories that Scan
+// skips unless told otherwise: those created by "terraform init" and git,
+// examples of how to call modules, and vendored code.
+var DefaultScanExclude = []string{".terraform", ".git", "examples", "vendor"}
+
+// ScanOptions controls which directories Scan visits, and how ScanContext
+// loads the modules it finds.
+type ScanOptions struct {
+	LoadOptions
+
+	// Exclude are glob patterns, in the syntax of path.Match, of directories
+	// to skip along with everything beneath them. A pattern without a slash
+	// matches the name of a directory at any depth, such as "examples" or
+	// ".*". A pattern with a slash matches the slash-separated path of a
+	// directory relative to the root, in which "**" matches any number of
+	// directories, such as "modules/*/test" or "**/fixtures/**".
+	//
+	// If Exclude is nil, DefaultScanExclude is used. The root itself is
+	// never excluded.
+	Exclude []string
+}
+
+// ScanReport is the result of Scan.
+type ScanReport struct {
+	// Root is the directory that was scanned.
+	Root string `json:"root"`
+
+	// Modules are the modules found, keyed by the slash-separated path of
+	// their directory relative to the root, which is "." for the root
+	// itself.
+	Modules map[string]*ScanResult `json:"modules"`
+
+	// Summary counts the diagnostics of all of the modules.
+	Summary *DiagnosticSummary `json:"summary"`
+
+	// Diagnostics are the problems found while scanning, such as
+	// directories that could not be read, rather than those found in the
+	// modules.
+	Diagnostics Diagnostics `json:"diagnostics,omitempty"`
+}
+
+// ScanResult is a module found by Scan.
+type ScanResult struct {
+	// Path is the slash-separated path of the module directory relative to
+	// the root of the scan.
+	Path string `json:"path"`
+
+	Module *Module `json:"module"`
+
+	// Summary counts the module's diagnostics.
+	Summary *DiagnosticSummary `json:"summary"`
+}
+
+// DiagnosticSummary counts a set of diagnostics.
+type DiagnosticSummary struct {
+	Modules  int `json:"modules,omitempty"`
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+
+	// Codes counts the diagnostics with each code.
+	Codes map[string]int `json:"codes,omitempty"`
+}
+
+// Summary counts the receiver's diagnostics by severity and code.
+func (diags Diagnostics) Summary() *DiagnosticSummary {
+	ret := &DiagnosticSummary{}
+	ret.add(diags)
+	return ret
+}
+
+func (s *DiagnosticSummary) add(diags Diagnostics) {
+	for _, diag := range diags {
+		if diag.Severity == DiagError {
+			s.Errors++
+		} else {
+			s.Warnings++
+		}
+		if diag.Code != "" {
+			if s.Codes == nil {
+				s.Codes = make(map[string]int)
+			}
+			s.Codes[diag.Code]++
+		}
+	}
+}
+
+// HasErrors returns true if the report has problems found while scanning,
+// or any of its modules has errors.
+func (r *ScanReport) HasErrors() bool {
+	return r.Diagnostics.HasErrors() || r.Summary.Errors > 0
+}
+
+// Scan walks the directories under the given root, skipping those excluded
+// by the given options, and loads each that contains Terraform
+// configuration files, as IsModuleDir reports, as a module. Unlike
+// LoadModuleTree, it finds modules whether or not another module calls
+// them, such as in a repository of many modules.
+//
+// Symbolic links to directories are not followed. The modules are loaded
+// one at a time, whatever the Parallelism of the given options, which may be
+// nil. Use ScanContext to load them concurrently.
+func Scan(fs FS, root string, opts *ScanOptions) *ScanReport {
+	serial := ScanOptions{}
+	if opts != nil {
+		serial = *opts
+	}
+	serial.Parallelism = 1
+	return ScanContext(context.Background(), fs, root, &serial)
+}
+
+// ScanContext is a variant of Scan that loads the modules, and their files,
+// concurrently as limited by the given options, which may be nil. The result
+// is the same however they are scheduled.
+//
+// If the given context is canceled or its deadline passes before the scan
+// is complete, the report has no modules, since some would be incomplete,
+// and its Diagnostics include an error with the code DiagCodeCanceled.
+func ScanContext(ctx context.Context, fs FS, root string, opts *ScanOptions) *ScanReport {
+	var loadOpts *LoadOptions
+	exclude := DefaultScanExclude
+	if opts != nil {
+		loadOpts = &opts.LoadOptions
+		if opts.Exclude != nil {
+			exclude = opts.Exclude
+		}
+	}
+	report := &ScanReport{
+		Root:    root,
+		Modules: make(map[string]*ScanResult),
+		Summary: &DiagnosticSummary{},
+	}
+	for _, pattern := range exclude {
+		if err := validScanPattern(pattern); err != nil {
+			report.Diagnostics = append(report.Diagnostics, Diagnostic{
+				Severity: DiagError,
+				Summary:  "Invalid exclude pattern",
+				Code:     DiagCodeScanPattern,
+				Detail:   fmt.Sprintf("The pattern %q is not a valid glob pattern: %s.", pattern, err),
+			})
+		}
+	}
+	if report.Diagnostics.HasErrors() {
+		return report
+	}
+
+	// The directories are walked in order, and then the modules found are
+	// loaded concurrently.
+	l := newLoader(ctx, fs, loadOpts)
+	var dirs, rels []string
+	var walk func(dir, rel string)
+	walk = func(dir, rel string) {
+		var infos []os.FileInfo
+		var err error
+		var isModule bool
+		if !l.do(func() {
+			infos, err = fs.ReadDir(dir)
+			if err == nil {
+				isModule = IsModuleDirOnFilesystem(fs, dir)
+			}
+		}) {
+			return
+		}
+		if err != nil {
+			report.Diagnostics = append(report.Diagnostics, Diagnostic{
+				Severity: DiagError,
+				Summary:  "Failed to read directory",
+				Code:     DiagCodeReadDir,
+				Detail:   fmt.Sprintf("Directory %s does not exist or cannot be read.", dir),
+			})
+			return
+		}
+		if isModule {
+			dirs = append(dirs, dir)
+			rels = append(rels, rel)
+		}
+
+		for _, info := range infos {
+			if !info.IsDir() {
+				continue
+			}
+			childRel := path.Join(rel, info.Name())
+			if excludeScanDir(exclude, childRel) {
+				continue
+			}
+			walk(filepath.Join(dir, info.Name()), childRel)
+		}
+	}
+	walk(root, ".")
+
+	results := make([]*ScanResult, len(dirs))
+	var wg sync.WaitGroup
+	for i, dir := range dirs {
+		wg.Add(1)
+		go func(i int, dir string) {
+			defer wg.Done()
+			module, diags := l.loadModule(dir)
+			results[i] = &ScanResult{
+				Path:    rels[i],
+				Module:  module,
+				Summary: diags.Summary(),
+			}
+		}(i, dir)
+	}
+	wg.Wait()
+
+	if canceled := l.canceled(); canceled != nil {
+		report.Diagnostics = append(report.Diagnostics, canceled...)
+		return report
+	}
+	for _, result := range results {
+		report.Modules[result.Path] = result
+		report.Summary.Modules++
+		report.Summary.add(result.Module.Diagnostics)
+	}
+	return report
+}
+
+// RenderScanJSON writes the given report as a single JSON document.
+func RenderScanJSON(w io.Writer, report *ScanReport) error {
+	j, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", j)
+	return err
+}
+
+// RenderScanJSONLines writes the given report as JSON Lines: a ScanResult
+// on each line for each of its modules, in order of their paths.
+func RenderScanJSONLines(w io.Writer, report *ScanReport) error {
+	enc := json.NewEncoder(w)
+	for _, rel := range SortedKeysOfMap(report.Modules) {
+		if err := enc.Encode(report.Modules[rel]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// excludeScanDir reports whether the directory with the given path relative
+// to the root of a scan matches any of the given patterns.
+func excludeScanDir(patterns []string, rel string) bool {
+	segments := strings.Split(rel, "/")
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(pattern, "/")
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, segments[len(segments)-1]); ok {
+				return true
+			}
+			continue
+		}
+		if matchScanSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchScanSegments matches the segments of a pattern, in which "**"
+// matches any number of segments, against those of a path.
+func matchScanSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchScanSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchScanSegments(pattern[1:], segments[1:])
+}
+
+func validScanPattern(pattern string) error {
+	if strings.TrimSuffix(pattern, "/") == "" {
+		return fmt.Errorf("pattern is empty")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
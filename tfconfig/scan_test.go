@@ -0,0 +1,114 @@
+package tfconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var testScanFS = fstest.MapFS{
+	"repo/main.tf":                               {Data: []byte("module \"vpc\" {\n  source = \"./modules/vpc\"\n}\n")},
+	"repo/docs/README.md":                        {Data: []byte("# Docs\n")},
+	"repo/modules/vpc/main.tf":                   {Data: []byte("variable \"name\" {}\n")},
+	"repo/modules/vpc/examples/basic/main.tf":    {Data: []byte("module \"vpc\" {\n  source = \"../..\"\n}\n")},
+	"repo/modules/vpc/test/fixtures/app/main.tf": {Data: []byte("variable \"app\" {}\n")},
+	"repo/modules/broken/main.tf":                {Data: []byte("variable \"name\" {\n")},
+	"repo/.terraform/modules/vpc/main.tf":        {Data: []byte("variable \"name\" {}\n")},
+	"repo/vendor/example.com/mod/main.tf":        {Data: []byte("variable \"name\" {}\n")},
+}
+
+func TestScan(t *testing.T) {
+	report := Scan(FromIOFS(testScanFS), "repo", nil)
+	if len(report.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %s", report.Diagnostics)
+	}
+	got := SortedKeysOfMap(report.Modules)
+	want := []string{".", "modules/broken", "modules/vpc", "modules/vpc/test/fixtures/app"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong modules\ngot:  %s\nwant: %s", got, want)
+	}
+	if got, want := report.Modules["modules/vpc"].Module.Path, "repo/modules/vpc"; got != want {
+		t.Errorf("wrong module path %q; want %q", got, want)
+	}
+	broken := report.Modules["modules/broken"].Summary
+	if broken.Errors != 1 || broken.Codes[DiagCodeHCL] != 1 {
+		t.Errorf("wrong summary for broken module: %#v", broken)
+	}
+	if got, want := report.Summary, (&DiagnosticSummary{Modules: 4, Errors: 1, Codes: map[string]int{DiagCodeHCL: 1}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong summary\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if !report.HasErrors() {
+		t.Errorf("report has no errors")
+	}
+
+	var buf bytes.Buffer
+	if err := RenderScanJSONLines(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("wrong number of lines %d; want %d", len(lines), len(want))
+	}
+	for i, line := range lines {
+		var result struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("line %d: %s", i, err)
+		}
+		if result.Path != want[i] {
+			t.Errorf("line %d has path %q; want %q", i, result.Path, want[i])
+		}
+	}
+}
+
+func TestScanExclude(t *testing.T) {
+	report := Scan(FromIOFS(testScanFS), "repo", &ScanOptions{
+		Exclude: append([]string{"**/test/**", "modules/broken"}, DefaultScanExclude...),
+	})
+	got := SortedKeysOfMap(report.Modules)
+	want := []string{".", "modules/vpc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong modules\ngot:  %s\nwant: %s", got, want)
+	}
+
+	report = Scan(FromIOFS(testScanFS), "repo", &ScanOptions{Exclude: []string{}})
+	if got := len(report.Modules); got != 7 {
+		t.Errorf("found %d modules with no exclusions; want 7", got)
+	}
+
+	report = Scan(FromIOFS(testScanFS), "repo", &ScanOptions{Exclude: []string{"[a-"}})
+	if len(report.Diagnostics) != 1 || report.Diagnostics[0].Code != DiagCodeScanPattern {
+		t.Errorf("wrong diagnostics for invalid pattern: %#v", report.Diagnostics)
+	}
+}
+
+func TestExcludeScanDir(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"examples", "examples", true},
+		{"examples", "modules/vpc/examples", true},
+		{"examples", "modules/examples-vpc", false},
+		{".*", "modules/.cache", true},
+		{"modules/*", "modules/vpc", true},
+		{"modules/*", "modules/vpc/sub", false},
+		{"modules/*", "other/modules/vpc", false},
+		{"/modules/vpc/", "modules/vpc", true},
+		{"**/fixtures", "fixtures", true},
+		{"**/fixtures", "a/b/fixtures", true},
+		{"**/test/**", "modules/vpc/test", true},
+		{"**/test/**", "modules/vpc/test/app", true},
+		{"**/test/**", "modules/vpc/testing", false},
+	}
+	for _, test := range tests {
+		if got := excludeScanDir([]string{test.pattern}, test.rel); got != test.want {
+			t.Errorf("excludeScanDir(%q, %q) = %t; want %t", test.pattern, test.rel, got, test.want)
+		}
+	}
+}
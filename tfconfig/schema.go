@@ -34,6 +34,14 @@ var rootSchema = &hcl.BodySchema{
 			Type:       "module",
 			LabelNames: []string{"name"},
 		},
+		{
+			Type:       "locals",
+			LabelNames: nil,
+		},
+		{
+			Type:       "moved",
+			LabelNames: nil,
+		},
 	},
 }
 
@@ -92,6 +100,9 @@ var outputSchema = &hcl.BodySchema{
 		{
 			Name: "type",
 		},
+		{
+			Name: "depends_on",
+		},
 	},
 }
 
@@ -106,6 +117,9 @@ var moduleCallSchema = &hcl.BodySchema{
 		{
 			Name: "providers",
 		},
+		{
+			Name: "depends_on",
+		},
 	},
 }
 
@@ -114,5 +128,21 @@ var resourceSchema = &hcl.BodySchema{
 		{
 			Name: "provider",
 		},
+		{
+			Name: "depends_on",
+		},
+	},
+}
+
+var movedSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{
+			Name:     "from",
+			Required: true,
+		},
+		{
+			Name:     "to",
+			Required: true,
+		},
 	},
 }
@@ -0,0 +1,581 @@
+package tfconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ServerOptions controls the HTTP API returned by NewServer.
+type ServerOptions struct {
+	// Root is the directory that the server-local paths given in requests
+	// are relative to. If it is blank, modules can only be uploaded.
+	Root string
+
+	// Metadata maps the names that requests to /enrich, /lint and
+	// /validate-tfvars may choose to the provider metadata files they name,
+	// which are read for each request.
+	Metadata map[string]string
+
+	// Rules are the rules checked by /lint, along with LintRules.
+	Rules []Rule
+
+	// MaxRequestSize is the largest request body accepted, in bytes. If it
+	// is zero, bodies of up to 10 MiB are accepted.
+	MaxRequestSize int64
+
+	// MaxArchiveSize is the largest total size of the files in an uploaded
+	// archive, in bytes. If it is zero, it is ten times MaxRequestSize.
+	MaxArchiveSize int64
+
+	// Timeout is how long a request may take before the server gives up on
+	// it. If it is zero, requests may take 30 seconds.
+	Timeout time.Duration
+
+	// LoadOptions are used to load the modules in each request.
+	LoadOptions LoadOptions
+}
+
+// NewServer returns an HTTP handler that inspects modules, serving these
+// endpoints:
+//
+//	GET  /health          reports that the server is running
+//	POST /inspect         returns the module as JSON, as with --json
+//	POST /enrich          returns the module enriched with provider metadata
+//	POST /validate-tfvars validates a .tfvars file against the module
+//	POST /lint            returns the problems found by the lint rules
+//	POST /diff            compares two modules
+//
+// The module is either uploaded as a zip or gzipped tar archive, as the
+// whole request body or as the "module" part of a multipart/form-data body,
+// or named by the "path" parameter as a directory or archive beneath Root.
+// The "subdir" parameter selects a directory within the archive or path.
+// /diff takes its modules from the "old" and "new" parts or parameters, with
+// the "old_subdir" and "new_subdir" parameters, and /validate-tfvars takes
+// the .tfvars file from the "tfvars" part, or the whole request body when
+// the module is named by a path, treating it as JSON if the part's file
+// name ends in ".json" or the "json" parameter is true.
+//
+// Parameters are given in the URL, or as fields of a multipart body. /enrich
+// takes the name of its metadata file from the "metadata" parameter, which
+// may be left out if there is only one. /lint and /validate-tfvars accept
+// the "metadata" parameter too, to enrich the module before checking it, as
+// lint --metadata does. /lint accepts the "enable" and "disable"
+// parameters, as comma-separated lists of rule IDs, and "recursive" to check
+// the modules that the module calls too.
+//
+// /lint and /validate-tfvars return diagnostics as JSON, as lint --json does,
+// and /diff returns a diff as diff --json does. Requests that fail return a
+// JSON object whose "error" describes the problem, along with any
+// "diagnostics" that caused it.
+func NewServer(opts *ServerOptions) http.Handler {
+	s := &server{}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.MaxRequestSize <= 0 {
+		s.opts.MaxRequestSize = 10 << 20
+	}
+	if s.opts.MaxArchiveSize <= 0 {
+		s.opts.MaxArchiveSize = 10 * s.opts.MaxRequestSize
+	}
+	if s.opts.Timeout <= 0 {
+		s.opts.Timeout = 30 * time.Second
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/inspect", s.handler(s.handleInspect))
+	mux.Handle("/enrich", s.handler(s.handleEnrich))
+	mux.Handle("/validate-tfvars", s.handler(s.handleValidateTfvars))
+	mux.Handle("/lint", s.handler(s.handleLint))
+	mux.Handle("/diff", s.handler(s.handleDiff))
+	return mux
+}
+
+type server struct {
+	opts ServerOptions
+}
+
+// serverError is an error returned by a request handler, with the status to
+// respond with.
+type serverError struct {
+	status      int
+	message     string
+	diagnostics Diagnostics
+}
+
+func (e *serverError) Error() string {
+	return e.message
+}
+
+func newServerError(status int, format string, args ...interface{}) *serverError {
+	return &serverError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// serverRequest is a request to one of the endpoints that inspect modules,
+// with its body read.
+type serverRequest struct {
+	*http.Request
+
+	// files are the files uploaded with the request, keyed by the names of
+	// their parts, or by the name of the endpoint's main file if the body
+	// isn't multipart.
+	files map[string]*serverFile
+}
+
+type serverFile struct {
+	name string
+	data []byte
+}
+
+// handler returns a handler that reads the body of each POST request and
+// passes it to the given function, writing the value it returns as JSON,
+// or its error, and gives up once the server's timeout has passed.
+func (s *server) handler(fn func(r *serverRequest) (interface{}, error)) http.Handler {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeServerError(w, newServerError(http.StatusMethodNotAllowed, "%s requires a POST request", r.URL.Path))
+			return
+		}
+		req, err := s.readRequest(r)
+		if err != nil {
+			writeServerError(w, err)
+			return
+		}
+		ret, err := fn(req)
+		if err == nil {
+			err = r.Context().Err()
+		}
+		if err != nil {
+			writeServerError(w, err)
+			return
+		}
+		writeServerJSON(w, http.StatusOK, ret)
+	})
+	return http.TimeoutHandler(h, s.opts.Timeout, `{"error": "request timed out"}`)
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeServerJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *server) handleInspect(r *serverRequest) (interface{}, error) {
+	fsys, dir, err := s.openModule(r, "module", "path", "subdir")
+	if err != nil {
+		return nil, err
+	}
+	module, _ := LoadModuleContext(r.Context(), fsys, dir, &s.opts.LoadOptions)
+	return module, nil
+}
+
+func (s *server) handleEnrich(r *serverRequest) (interface{}, error) {
+	metadata, err := s.metadata(r, true)
+	if err != nil {
+		return nil, err
+	}
+	fsys, dir, err := s.openModule(r, "module", "path", "subdir")
+	if err != nil {
+		return nil, err
+	}
+	module, _, err := s.loadModule(r, fsys, dir, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+func (s *server) handleValidateTfvars(r *serverRequest) (interface{}, error) {
+	fsys, dir, err := s.openModule(r, "module", "path", "subdir")
+	if err != nil {
+		return nil, err
+	}
+	f := r.files["tfvars"]
+	if f == nil {
+		return nil, newServerError(http.StatusBadRequest, "no tfvars file was given")
+	}
+	filename := f.name
+	if r.FormValue("json") == "true" && !strings.HasSuffix(filename, ".json") {
+		filename += ".json"
+	}
+
+	metadata, err := s.metadata(r, false)
+	if err != nil {
+		return nil, err
+	}
+	module, diags, err := s.loadModule(r, fsys, dir, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if diags.HasErrors() {
+		return nil, &serverError{status: http.StatusUnprocessableEntity, message: "failed to load module", diagnostics: diags}
+	}
+	diags = ValidateTfvars(module, f.data, filename)
+	if diags == nil {
+		diags = Diagnostics{}
+	}
+	return diags, nil
+}
+
+func (s *server) handleLint(r *serverRequest) (interface{}, error) {
+	rules, err := SelectRules(append(append([]Rule(nil), LintRules...), s.opts.Rules...), serverList(r.FormValue("enable")), serverList(r.FormValue("disable")))
+	if err != nil {
+		return nil, newServerError(http.StatusBadRequest, "%s", err)
+	}
+	metadata, err := s.metadata(r, false)
+	if err != nil {
+		return nil, err
+	}
+	fsys, dir, err := s.openModule(r, "module", "path", "subdir")
+	if err != nil {
+		return nil, err
+	}
+
+	// Rules may check the metadata, such as the cloud data types of
+	// variables.
+	var diags Diagnostics
+	if r.FormValue("recursive") == "true" {
+		var tree *ModuleTree
+		tree, diags = LoadModuleTreeContext(r.Context(), fsys, dir, &s.opts.LoadOptions)
+		if metadata != nil {
+			// The problems found while loading the tree have been
+			// reported already, so only those with the metadata are.
+			root, _, err := s.loadModule(r, fsys, dir, metadata)
+			if err != nil {
+				return nil, err
+			}
+			tree.Walk(func(t *ModuleTree) error {
+				if t == tree {
+					t.Module = root
+				} else if module, moduleDiags := LoadIBMModuleFromFilesystem(fsys, t.Module.Path, metadata, nil); !moduleDiags.HasErrors() {
+					t.Module = module
+				}
+				return nil
+			})
+		}
+		diags = append(diags, LintTree(tree, rules)...)
+	} else {
+		var module *Module
+		module, diags, err = s.loadModule(r, fsys, dir, metadata)
+		if err != nil {
+			return nil, err
+		}
+		diags = append(diags, Lint(module, rules)...)
+	}
+	if diags == nil {
+		diags = Diagnostics{}
+	}
+	return diags, nil
+}
+
+func (s *server) handleDiff(r *serverRequest) (interface{}, error) {
+	oldFS, oldDir, err := s.openModule(r, "old", "old", "old_subdir")
+	if err != nil {
+		return nil, err
+	}
+	newFS, newDir, err := s.openModule(r, "new", "new", "new_subdir")
+	if err != nil {
+		return nil, err
+	}
+	oldModule, oldDiags := LoadModuleContext(r.Context(), oldFS, oldDir, &s.opts.LoadOptions)
+	newModule, newDiags := LoadModuleContext(r.Context(), newFS, newDir, &s.opts.LoadOptions)
+	if diags := append(oldDiags, newDiags...); diags.HasErrors() {
+		return nil, &serverError{status: http.StatusUnprocessableEntity, message: "failed to load modules", diagnostics: diags}
+	}
+	return Diff(oldModule, newModule), nil
+}
+
+// metadata returns the provider metadata named by the "metadata" parameter
+// of the given request, or nil if there is none. If the metadata is
+// required, the parameter may be left out when the server has only one.
+func (s *server) metadata(r *serverRequest, required bool) (ProviderMetadata, error) {
+	name := r.FormValue("metadata")
+	if name == "" && !required {
+		return nil, nil
+	}
+	if name == "" && len(s.opts.Metadata) == 1 {
+		for only := range s.opts.Metadata {
+			name = only
+		}
+	}
+	filename, ok := s.opts.Metadata[name]
+	if !ok {
+		return nil, newServerError(http.StatusBadRequest, "unknown metadata %q; choose one of %s", name, strings.Join(SortedKeysOfMap(s.opts.Metadata), ", "))
+	}
+	metadata, diags := LoadProviderMetadataFile(NewOsFs(), filename)
+	if diags.HasErrors() {
+		return nil, &serverError{status: http.StatusInternalServerError, message: "failed to read metadata", diagnostics: diags}
+	}
+	return metadata, nil
+}
+
+// loadModule loads the module in the given directory, enriched with the
+// given provider metadata unless it is nil, in which case the module need
+// not have been initialized. It fails only if there is no module at all.
+func (s *server) loadModule(r *serverRequest, fsys FS, dir string, metadata ProviderMetadata) (*Module, Diagnostics, error) {
+	if metadata == nil {
+		module, diags := LoadModuleContext(r.Context(), fsys, dir, &s.opts.LoadOptions)
+		return module, diags, nil
+	}
+	module, diags := checkForInitDirectoryAndLoadIBMModule(fsys, dir, metadata, s.opts.LoadOptions.Cache)
+	if module == nil {
+		return nil, nil, &serverError{status: http.StatusUnprocessableEntity, message: "failed to load module", diagnostics: diags}
+	}
+	module.Diagnostics = diags
+	return module, diags, nil
+}
+
+// readRequest reads the body of the given request, failing if it is larger
+// than allowed. The body of a request whose module is named by a path is
+// the .tfvars file for /validate-tfvars, and otherwise is the module.
+func (s *server) readRequest(r *http.Request) (*serverRequest, error) {
+	src, err := ioutil.ReadAll(io.LimitReader(r.Body, s.opts.MaxRequestSize+1))
+	if err != nil {
+		return nil, newServerError(http.StatusBadRequest, "failed to read request: %s", err)
+	}
+	if int64(len(src)) > s.opts.MaxRequestSize {
+		return nil, newServerError(http.StatusRequestEntityTooLarge, "request is larger than %d bytes", s.opts.MaxRequestSize)
+	}
+	req := &serverRequest{Request: r, files: make(map[string]*serverFile)}
+
+	// Parameters are read from the URL and from multipart bodies only, so
+	// that files posted as encoded forms by clients such as curl are read
+	// as files.
+	query, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return nil, newServerError(http.StatusBadRequest, "invalid parameters: %s", err)
+	}
+	r.Form = query
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "multipart/form-data":
+		mr := multipart.NewReader(bytes.NewReader(src), params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, newServerError(http.StatusBadRequest, "invalid multipart request: %s", err)
+			}
+			data, err := ioutil.ReadAll(part)
+			if err != nil {
+				return nil, newServerError(http.StatusBadRequest, "invalid multipart request: %s", err)
+			}
+			if part.FileName() == "" {
+				// Other parts are parameters, as in the URL.
+				r.Form.Add(part.FormName(), string(data))
+				continue
+			}
+			req.files[part.FormName()] = &serverFile{name: path.Base(part.FileName()), data: data}
+		}
+	case len(src) > 0:
+		if r.URL.Path == "/validate-tfvars" && r.Form.Get("path") != "" {
+			req.files["tfvars"] = &serverFile{name: "terraform.tfvars", data: src}
+		} else {
+			req.files["module"] = &serverFile{name: "module", data: src}
+		}
+	}
+	return req, nil
+}
+
+// openModule returns the FS and directory of the module uploaded as the
+// given part of the given request, or named by the given path parameter,
+// within the directory named by the given subdir parameter.
+func (s *server) openModule(r *serverRequest, part, pathParam, subdirParam string) (FS, string, error) {
+	subdir := r.FormValue(subdirParam)
+	if subdir != "" && !fs.ValidPath(subdir) {
+		return nil, "", newServerError(http.StatusBadRequest, "invalid %s %q", subdirParam, subdir)
+	}
+	dir := "."
+	if subdir != "" {
+		dir = filepath.FromSlash(subdir)
+	}
+
+	if f := r.files[part]; f != nil {
+		fsys, err := readArchive(f.data, s.opts.MaxArchiveSize)
+		if err == errArchiveTooLarge {
+			return nil, "", newServerError(http.StatusRequestEntityTooLarge, "%s archive is larger than %d bytes when extracted", part, s.opts.MaxArchiveSize)
+		}
+		if err != nil {
+			return nil, "", newServerError(http.StatusBadRequest, "invalid %s archive: %s", part, err)
+		}
+		return fsys, dir, nil
+	}
+
+	p := r.FormValue(pathParam)
+	if p == "" {
+		return nil, "", newServerError(http.StatusBadRequest, "no %s archive or %s parameter was given", part, pathParam)
+	}
+	if s.opts.Root == "" {
+		return nil, "", newServerError(http.StatusForbidden, "modules must be uploaded to this server")
+	}
+	// Paths are read from an FS rooted at Root, which refuses paths that
+	// are absolute or that refer to a parent of Root, including through
+	// symbolic links.
+	p = path.Clean(p)
+	if !fs.ValidPath(p) {
+		return nil, "", newServerError(http.StatusBadRequest, "invalid %s %q; it must be relative and beneath the server's root", pathParam, p)
+	}
+	root, err := newServerRootFS(s.opts.Root)
+	if err != nil {
+		return nil, "", newServerError(http.StatusInternalServerError, "server root cannot be read")
+	}
+	if IsArchivePath(p) {
+		src, err := root.ReadFile(filepath.FromSlash(p))
+		if errors.Is(err, fs.ErrPermission) {
+			return nil, "", newServerError(http.StatusForbidden, "%s %q is outside the server's root", pathParam, p)
+		}
+		if err != nil {
+			return nil, "", newServerError(http.StatusNotFound, "%s %q cannot be read", pathParam, p)
+		}
+		fsys, err := readArchive(src, s.opts.MaxArchiveSize)
+		if err != nil {
+			return nil, "", newServerError(http.StatusUnprocessableEntity, "invalid archive %q: %s", p, err)
+		}
+		return fsys, dir, nil
+	}
+	dir = filepath.Join(filepath.FromSlash(p), dir)
+	if _, err := root.ReadDir(dir); errors.Is(err, fs.ErrPermission) {
+		return nil, "", newServerError(http.StatusForbidden, "%s %q is outside the server's root", pathParam, filepath.ToSlash(dir))
+	} else if err != nil {
+		return nil, "", newServerError(http.StatusNotFound, "%s %q is not a directory", pathParam, filepath.ToSlash(dir))
+	}
+	return root, dir, nil
+}
+
+// serverRootFS reads the files beneath a root directory. Unlike os.DirFS,
+// it resolves symbolic links and refuses to read any whose target is
+// outside the root, so that clients can't use a link in a module, or in the
+// paths they name, to read other files on the server.
+type serverRootFS struct {
+	// root is the absolute path of the root, with symbolic links resolved.
+	root string
+}
+
+func newServerRootFS(root string) (*serverRootFS, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	return &serverRootFS{root: root}, nil
+}
+
+// resolve returns the path of the named file with symbolic links resolved,
+// failing with fs.ErrPermission if it is outside the root. Errors name the
+// file by the given name rather than by its path on the server.
+func (r *serverRootFS) resolve(op, name string) (string, error) {
+	real, err := filepath.EvalSymlinks(filepath.Join(r.root, name))
+	if err != nil {
+		return "", serverPathError(op, name, err)
+	}
+	rel, err := filepath.Rel(r.root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+	return real, nil
+}
+
+func (r *serverRootFS) Open(name string) (File, error) {
+	real, err := r.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(real)
+	if err != nil {
+		return nil, serverPathError("open", name, err)
+	}
+	return f, nil
+}
+
+func (r *serverRootFS) ReadFile(name string) ([]byte, error) {
+	real, err := r.resolve("read", name)
+	if err != nil {
+		return nil, err
+	}
+	src, err := ioutil.ReadFile(real)
+	if err != nil {
+		return nil, serverPathError("read", name, err)
+	}
+	return src, nil
+}
+
+func (r *serverRootFS) ReadDir(name string) ([]os.FileInfo, error) {
+	real, err := r.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(real)
+	if err != nil {
+		return nil, serverPathError("readdir", name, err)
+	}
+	return infos, nil
+}
+
+// serverPathError returns an error for the given operation on the named
+// file with the cause of the given error, which may name the file by its
+// path on the server.
+func serverPathError(op, name string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// serverList splits the given comma-separated list.
+func serverList(s string) []string {
+	var ret []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func writeServerError(w http.ResponseWriter, err error) {
+	var serr *serverError
+	if !errors.As(err, &serr) {
+		status := http.StatusInternalServerError
+		if err == context.DeadlineExceeded || err == context.Canceled {
+			status = http.StatusServiceUnavailable
+		}
+		serr = &serverError{status: status, message: err.Error()}
+	}
+	writeServerJSON(w, serr.status, struct {
+		Error       string      `json:"error"`
+		Diagnostics Diagnostics `json:"diagnostics,omitempty"`
+	}{serr.message, serr.diagnostics})
+}
+
+func writeServerJSON(w http.ResponseWriter, status int, v interface{}) {
+	j, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		status = http.StatusInternalServerError
+		j = []byte(`{"error": "failed to produce JSON"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(j)
+	w.Write([]byte{'\n'})
+}
@@ -0,0 +1,342 @@
+package tfconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerInspect(t *testing.T) {
+	srv := httptest.NewServer(NewServer(&ServerOptions{Root: "testdata"}))
+	defer srv.Close()
+
+	// Uploaded archives are inspected as with LoadModuleFromArchive.
+	for _, ext := range []string{".zip", ".tar.gz"} {
+		archive := writeTestArchive(t, filepath.Join("testdata", "module-tree"), ext)
+		src, err := ioutil.ReadFile(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := LoadModuleFromArchive(archive + "//network")
+		body := serverPost(t, srv.URL+"/inspect?subdir=network", "application/octet-stream", src, http.StatusOK)
+		assertServerJSON(t, body, want)
+	}
+
+	// Paths are relative to the server's root.
+	want, _ := LoadModuleFromIOFS(os.DirFS("testdata"), "basics")
+	body := serverPost(t, srv.URL+"/inspect?path=basics", "", nil, http.StatusOK)
+	assertServerJSON(t, body, want)
+
+	serverPost(t, srv.URL+"/inspect?path=../load.go", "", nil, http.StatusBadRequest)
+	serverPost(t, srv.URL+"/inspect?path=missing", "", nil, http.StatusNotFound)
+	serverPost(t, srv.URL+"/inspect", "", nil, http.StatusBadRequest)
+	serverPost(t, srv.URL+"/inspect", "application/zip", []byte("not a zip"), http.StatusBadRequest)
+
+	resp, err := http.Get(srv.URL + "/inspect?path=basics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("wrong status %d for GET; want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServerEnrich(t *testing.T) {
+	src := []byte(`{"Resources": {"ibm_is_subnet": [{"name": "zone", "description": "Zone of the subnet"}]}}`)
+	metadataFile := filepath.Join(t.TempDir(), "metadata.json")
+	if err := ioutil.WriteFile(metadataFile, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(NewServer(&ServerOptions{
+		Root:     "testdata",
+		Metadata: map[string]string{"ibm": metadataFile},
+	}))
+	defer srv.Close()
+
+	metadata, _ := ParseProviderMetadata(src)
+	want, _ := CheckForInitDirectoryAndLoadIBMModuleFromFilesystem(FromIOFS(os.DirFS("testdata")), "ibm-metadata", metadata)
+	body := serverPost(t, srv.URL+"/enrich?path=ibm-metadata&metadata=ibm", "", nil, http.StatusOK)
+	assertServerJSON(t, body, want)
+
+	// The only metadata is used by default.
+	body = serverPost(t, srv.URL+"/enrich?path=ibm-metadata", "", nil, http.StatusOK)
+	assertServerJSON(t, body, want)
+
+	serverPost(t, srv.URL+"/enrich?path=ibm-metadata&metadata=other", "", nil, http.StatusBadRequest)
+	body = serverPost(t, srv.URL+"/enrich?path=basics", "", nil, http.StatusUnprocessableEntity)
+	if !strings.Contains(string(body), DiagCodeNotInitialized) {
+		t.Errorf("error has no diagnostics: %s", body)
+	}
+}
+
+func TestServerValidateTfvars(t *testing.T) {
+	srv := httptest.NewServer(NewServer(&ServerOptions{Root: "testdata"}))
+	defer srv.Close()
+
+	tfvars := []byte("region = \"us-south\"\nundeclared = 1\n")
+	module, _ := LoadModuleFromIOFS(os.DirFS("testdata"), "lint")
+	want := ValidateTfvars(module, tfvars, "terraform.tfvars")
+	if len(want) == 0 {
+		t.Fatal("no diagnostics to compare")
+	}
+
+	body := serverPost(t, srv.URL+"/validate-tfvars?path=lint", "text/plain", tfvars, http.StatusOK)
+	assertServerJSON(t, body, want)
+
+	// Archives are uploaded along with the tfvars file.
+	archive := writeTestArchive(t, filepath.Join("testdata", "lint"), ".zip")
+	src, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, _ = LoadModuleFromArchive(archive)
+	want = ValidateTfvars(module, tfvars, "terraform.tfvars")
+	contentType, body := serverMultipart(t, map[string][]byte{"module": src, "terraform.tfvars": tfvars}, map[string]string{"terraform.tfvars": "tfvars"})
+	body = serverPost(t, srv.URL+"/validate-tfvars", contentType, body, http.StatusOK)
+	assertServerJSON(t, body, want)
+
+	serverPost(t, srv.URL+"/validate-tfvars?path=lint", "", nil, http.StatusBadRequest)
+}
+
+func TestServerValidateTfvarsMetadata(t *testing.T) {
+	metadataFile := filepath.Join("testdata", "tfvars", "tfvars.metadata.json")
+	srv := httptest.NewServer(NewServer(&ServerOptions{
+		Root:     "testdata",
+		Metadata: map[string]string{"ibm": metadataFile},
+	}))
+	defer srv.Close()
+
+	// The values allowed for region come from the metadata, so they are
+	// only checked when it is chosen.
+	tfvars := []byte("region = \"mars\"\nname = \"app\"\nunused = 1\napi_key = \"secret\"\n")
+	module, _ := CheckForInitDirectoryAndLoadIBMModule(filepath.Join("testdata", "tfvars"), metadataFile)
+	want := ValidateTfvars(module, tfvars, "terraform.tfvars")
+	if len(want) != 1 {
+		t.Fatalf("wrong diagnostics to compare: %s", want)
+	}
+	body := serverPost(t, srv.URL+"/validate-tfvars?path=tfvars&metadata=ibm", "text/plain", tfvars, http.StatusOK)
+	assertServerJSON(t, body, want)
+
+	body = serverPost(t, srv.URL+"/validate-tfvars?path=tfvars", "text/plain", tfvars, http.StatusOK)
+	assertServerJSON(t, body, Diagnostics{})
+
+	serverPost(t, srv.URL+"/validate-tfvars?path=tfvars&metadata=other", "text/plain", tfvars, http.StatusBadRequest)
+	serverPost(t, srv.URL+"/validate-tfvars?path=lint&metadata=ibm", "text/plain", tfvars, http.StatusUnprocessableEntity)
+}
+
+func TestServerLint(t *testing.T) {
+	srv := httptest.NewServer(NewServer(&ServerOptions{Root: "testdata"}))
+	defer srv.Close()
+
+	module, diags := LoadModuleFromIOFS(os.DirFS("testdata"), "lint")
+	want := append(diags, Lint(module, LintRules)...)
+	body := serverPost(t, srv.URL+"/lint?path=lint", "", nil, http.StatusOK)
+	assertServerJSON(t, body, want)
+
+	rules, _ := SelectRules(LintRules, []string{"variable_description"}, nil)
+	want = append(diags, Lint(module, rules)...)
+	body = serverPost(t, srv.URL+"/lint?path=lint&enable=variable_description", "", nil, http.StatusOK)
+	assertServerJSON(t, body, want)
+
+	serverPost(t, srv.URL+"/lint?path=lint&enable=missing", "", nil, http.StatusBadRequest)
+}
+
+func TestServerLintMetadata(t *testing.T) {
+	path := filepath.Join("testdata", "policy")
+	rules, diags := LoadRuleFile(filepath.Join(path, "policy.rules.hcl"))
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	metadataFile := filepath.Join(path, "policy.metadata.json")
+	srv := httptest.NewServer(NewServer(&ServerOptions{
+		Root:     "testdata",
+		Metadata: map[string]string{"ibm": metadataFile},
+		Rules:    rules,
+	}))
+	defer srv.Close()
+
+	// The policy rules check the cloud data types of variables, which come
+	// from the metadata.
+	src, err := ioutil.ReadFile(metadataFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, _ := ParseProviderMetadata(src)
+	module, diags := CheckForInitDirectoryAndLoadIBMModuleFromFilesystem(FromIOFS(os.DirFS("testdata")), "policy", metadata)
+	selected, _ := SelectRules(append(append([]Rule(nil), LintRules...), rules...), []string{"instance_resource_group"}, nil)
+	want := append(diags, Lint(module, selected)...)
+	body := serverPost(t, srv.URL+"/lint?path=policy&metadata=ibm&enable=instance_resource_group", "", nil, http.StatusOK)
+	assertServerJSON(t, body, want)
+	body = serverPost(t, srv.URL+"/lint?path=policy&metadata=ibm&enable=instance_resource_group&recursive=true", "", nil, http.StatusOK)
+	if strings.Contains(string(body), "ibm_is_instance.good") || !strings.Contains(string(body), "ibm_is_instance.bad") {
+		t.Errorf("wrong problems with the tree:\n%s", body)
+	}
+
+	// Without it, every instance breaks the rule.
+	body = serverPost(t, srv.URL+"/lint?path=policy&enable=instance_resource_group", "", nil, http.StatusOK)
+	if !strings.Contains(string(body), "ibm_is_instance.good") {
+		t.Errorf("wrong problems without metadata:\n%s", body)
+	}
+}
+
+func TestServerDiff(t *testing.T) {
+	srv := httptest.NewServer(NewServer(&ServerOptions{Root: "testdata"}))
+	defer srv.Close()
+
+	oldModule, _ := LoadModuleFromIOFS(os.DirFS("testdata"), "diff-old")
+	newModule, _ := LoadModuleFromIOFS(os.DirFS("testdata"), "diff-new")
+	want := Diff(oldModule, newModule)
+	body := serverPost(t, srv.URL+"/diff?old=diff-old&new=diff-new", "", nil, http.StatusOK)
+	assertServerJSON(t, body, want)
+
+	// Either module may be uploaded instead.
+	archive := writeTestArchive(t, filepath.Join("testdata", "diff-new"), ".tar.gz")
+	src, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contentType, body := serverMultipart(t, map[string][]byte{"new.tar.gz": src}, map[string]string{"new.tar.gz": "new"})
+	body = serverPost(t, srv.URL+"/diff?old=diff-old", contentType, body, http.StatusOK)
+	assertServerJSON(t, body, want)
+}
+
+func TestServerSymlinks(t *testing.T) {
+	outside := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(outside, "main.tf"), []byte("variable \"secret\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "module"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "module", "main.tf"), []byte("variable \"name\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		filepath.Join(root, "module", "secret.tf"): filepath.Join(outside, "main.tf"),
+		filepath.Join(root, "outside"):             outside,
+		filepath.Join(root, "inside"):              filepath.Join(root, "module"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, name); err != nil {
+			t.Skipf("cannot create symbolic links: %s", err)
+		}
+	}
+	srv := httptest.NewServer(NewServer(&ServerOptions{Root: root}))
+	defer srv.Close()
+
+	// Links may point within the root, but files outside it aren't read.
+	for _, path := range []string{"module", "inside"} {
+		body := serverPost(t, srv.URL+"/inspect?path="+path, "", nil, http.StatusOK)
+		if strings.Contains(string(body), "secret\"") || strings.Contains(string(body), outside) {
+			t.Errorf("module %s includes a file outside the root:\n%s", path, body)
+		}
+		if !strings.Contains(string(body), "\"name\"") {
+			t.Errorf("module %s has no variables:\n%s", path, body)
+		}
+	}
+	serverPost(t, srv.URL+"/inspect?path=outside", "", nil, http.StatusForbidden)
+}
+
+func TestServerLimits(t *testing.T) {
+	archive := writeTestArchive(t, filepath.Join("testdata", "module-tree"), ".zip")
+	src, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(NewServer(&ServerOptions{MaxRequestSize: int64(len(src) - 1)}))
+	serverPost(t, srv.URL+"/inspect", "application/zip", src, http.StatusRequestEntityTooLarge)
+	srv.Close()
+
+	srv = httptest.NewServer(NewServer(&ServerOptions{MaxArchiveSize: 10}))
+	serverPost(t, srv.URL+"/inspect", "application/zip", src, http.StatusRequestEntityTooLarge)
+	serverPost(t, srv.URL+"/inspect?path=basics", "", nil, http.StatusForbidden)
+	srv.Close()
+
+	srv = httptest.NewServer(NewServer(&ServerOptions{Timeout: time.Nanosecond}))
+	serverPost(t, srv.URL+"/inspect", "application/zip", src, http.StatusServiceUnavailable)
+	srv.Close()
+
+	srv = httptest.NewServer(NewServer(nil))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("wrong health status %d", resp.StatusCode)
+	}
+}
+
+// serverPost posts the given body to the given URL, failing unless the
+// response has the given status, and returns the response body.
+func serverPost(t *testing.T, url, contentType string, body []byte, status int) []byte {
+	t.Helper()
+	resp, err := http.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != status {
+		t.Errorf("wrong status %d from %s; want %d\n%s", resp.StatusCode, url, status, got)
+	}
+	return got
+}
+
+// serverMultipart returns the content type and body of a multipart form
+// with the given files, keyed by file name, in the parts named by the given
+// map.
+func serverMultipart(t *testing.T, files map[string][]byte, parts map[string]string) (string, []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, src := range files {
+		part := parts[name]
+		if part == "" {
+			part = name
+		}
+		w, err := mw.CreateFormFile(part, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(src)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return mw.FormDataContentType(), buf.Bytes()
+}
+
+// assertServerJSON checks that the given response body is the JSON form of
+// the given value.
+func assertServerJSON(t *testing.T, body []byte, want interface{}) {
+	t.Helper()
+	var got interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("invalid JSON: %s\n%s", err, body)
+	}
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wantVal interface{}
+	if err := json.Unmarshal(wantJSON, &wantVal); err != nil {
+		t.Fatal(err)
+	}
+	assertSameJSON(t, got, wantVal)
+}
@@ -16,6 +16,26 @@ type SourcePos struct {
 	Line     int    `json:"line"`
 }
 
+// SourceRange is a range of characters in a source file, as reported by the
+// HCL parser. Unlike SourcePos it includes columns, whose meaning is that
+// of HCL version 2: lines and columns count from one, and columns count
+// grapheme clusters. The End point is exclusive.
+type SourceRange struct {
+	Filename string      `json:"filename"`
+	Start    SourcePoint `json:"start"`
+	End      SourcePoint `json:"end"`
+}
+
+// SourcePoint is a single point in a source file.
+type SourcePoint struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+
+	// Byte is the offset of the point from the start of the file, in
+	// bytes.
+	Byte int `json:"byte"`
+}
+
 func sourcePos(filename string, line int) SourcePos {
 	return SourcePos{
 		Filename: filename,
@@ -48,3 +68,19 @@ func sourcePosLegacyHCL(pos legacyhcltoken.Pos, filename string) SourcePos {
 		Line:     pos.Line,
 	}
 }
+
+func sourceRangeHCL(rng hcl.Range) *SourceRange {
+	return &SourceRange{
+		Filename: rng.Filename,
+		Start: SourcePoint{
+			Line:   rng.Start.Line,
+			Column: rng.Start.Column,
+			Byte:   rng.Start.Byte,
+		},
+		End: SourcePoint{
+			Line:   rng.End.Line,
+			Column: rng.End.Column,
+			Byte:   rng.End.Byte,
+		},
+	}
+}
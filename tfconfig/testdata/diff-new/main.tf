@@ -0,0 +1,63 @@
+terraform {
+  required_version = ">= 1.3.0"
+
+  required_providers {
+    ibm = {
+      source  = "IBM-Cloud/ibm"
+      version = ">= 1.40.0, < 2.0.0"
+    }
+  }
+}
+
+variable "name" {
+  type        = string
+  description = "Prefix for the names of the resources"
+}
+
+variable "zones" {
+  type    = number
+  default = 3
+}
+
+variable "tags" {
+  type    = set(string)
+  default = []
+}
+
+variable "api_key" {
+  type      = string
+  sensitive = true
+}
+
+variable "region" {
+  type = string
+}
+
+variable "resource_group" {
+  type    = string
+  default = null
+}
+
+resource "ibm_is_vpc" "vpc" {
+  name = var.name
+}
+
+resource "ibm_is_subnet" "subnets" {
+  count = var.zones
+  name  = "${var.name}-${count.index}"
+  vpc   = ibm_is_vpc.vpc.id
+}
+
+moved {
+  from = ibm_is_subnet.subnet
+  to   = ibm_is_subnet.subnets[0]
+}
+
+output "vpc_id" {
+  value     = ibm_is_vpc.vpc.id
+  sensitive = true
+}
+
+output "vpc_crn" {
+  value = ibm_is_vpc.vpc.crn
+}
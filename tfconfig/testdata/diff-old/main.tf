@@ -0,0 +1,55 @@
+terraform {
+  required_version = ">= 1.0.0"
+
+  required_providers {
+    ibm = {
+      source  = "IBM-Cloud/ibm"
+      version = ">= 1.40.0"
+    }
+  }
+}
+
+variable "name" {
+  type        = string
+  description = "Prefix for the names of all resources"
+}
+
+variable "zones" {
+  type    = number
+  default = 1
+}
+
+variable "tags" {
+  type    = list(string)
+  default = []
+}
+
+variable "api_key" {
+  type = string
+}
+
+variable "legacy" {
+  default = "unused"
+}
+
+resource "ibm_is_vpc" "vpc" {
+  name = var.name
+}
+
+resource "ibm_is_subnet" "subnet" {
+  name = var.name
+  vpc  = ibm_is_vpc.vpc.id
+}
+
+resource "ibm_is_public_gateway" "gateway" {
+  name = var.name
+  vpc  = ibm_is_vpc.vpc.id
+}
+
+output "vpc_id" {
+  value = ibm_is_vpc.vpc.id
+}
+
+output "subnet_id" {
+  value = ibm_is_subnet.subnet.id
+}
@@ -0,0 +1,7 @@
+variable "zone" {
+  type = string
+}
+
+resource "ibm_is_subnet" "this" {
+  zone = var.zone
+}
@@ -0,0 +1,17 @@
+variable "region" {
+  type = string
+}
+
+variable "vpc_name" {
+  type = string
+}
+
+resource "ibm_is_vpc" "this" {
+  name = var.vpc_name
+}
+
+module "subnet" {
+  source  = "terraform-ibm-modules/subnet/ibm//modules/basic"
+  version = "1.0.0"
+  zone    = var.region
+}
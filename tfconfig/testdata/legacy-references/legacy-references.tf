@@ -0,0 +1,41 @@
+variable "region"
+{
+  default = "us-south"
+}
+
+variable "name"
+{
+}
+
+provider "ibm"
+{
+  region = "${var.region}"
+}
+
+provider "ibm"
+{
+  alias  = "dr"
+  region = "eu-de"
+}
+
+locals
+{
+  vpc_name = "${var.name}-vpc"
+}
+
+resource "ibm_is_vpc" "vpc"
+{
+  name = "${local.vpc_name}"
+}
+
+module "dns"
+{
+  source = "./dns"
+  vpc    = "${ibm_is_vpc.vpc.id}"
+}
+
+output "vpc"
+{
+  value      = "${ibm_is_vpc.vpc.id}"
+  depends_on = ["module.dns"]
+}
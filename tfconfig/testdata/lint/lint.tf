@@ -0,0 +1,74 @@
+terraform {
+  required_providers {
+    ibm = {
+      source  = "IBM-Cloud/ibm"
+      version = ">= 1.40.0"
+    }
+  }
+}
+
+provider "ibm" {
+  region = var.region
+}
+
+variable "region" {
+  type        = string
+  description = "Region to deploy into"
+}
+
+variable "name" {
+  type = string
+}
+
+variable "unused" {
+  description = "Not used anywhere"
+}
+
+variable "api_key" {
+  type        = string
+  description = "API key for the workload"
+  sensitive   = true
+}
+
+locals {
+  credentials = "${var.name}:${var.api_key}"
+}
+
+resource "ibm_is_vpc" "vpc" {
+  name = var.name
+}
+
+resource "random_string" "suffix" {
+  length = 8
+}
+
+module "registry" {
+  source = "terraform-ibm-modules/vpc/ibm"
+}
+
+module "pinned" {
+  source  = "terraform-ibm-modules/vpc/ibm"
+  version = "1.0.0"
+}
+
+module "git" {
+  source = "github.com/example/module"
+}
+
+module "local" {
+  source = "./local"
+}
+
+output "vpc_id" {
+  description = "ID of the VPC"
+  value       = ibm_is_vpc.vpc.id
+}
+
+output "credentials" {
+  description = "Credentials for the workload"
+  value       = local.credentials
+}
+
+output "suffix" {
+  value = random_string.suffix.result
+}
@@ -0,0 +1,8 @@
+data "ibm_resource_group" "group" {
+  name = "default"
+}
+
+output "bucket" {
+  description = "Name of the bucket"
+  value       = "bucket"
+}
@@ -0,0 +1,21 @@
+variable "name" {
+  description = "Name prefix for all resources"
+}
+
+module "network" {
+  source = "./network"
+  name   = var.name
+}
+
+module "storage" {
+  source  = "example/storage/ibm"
+  version = "1.0.0"
+}
+
+module "missing" {
+  source = "example/missing/ibm"
+}
+
+output "subnet_id" {
+  value = module.network.subnet_id
+}
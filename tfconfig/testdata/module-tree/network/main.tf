@@ -0,0 +1,17 @@
+variable "name" {
+  type = string
+}
+
+resource "ibm_is_vpc" "this" {
+  name = var.name
+}
+
+module "subnet" {
+  source = "./subnet"
+  vpc    = ibm_is_vpc.this.id
+}
+
+output "subnet_id" {
+  description = "ID of the subnet"
+  value       = module.subnet.id
+}
@@ -0,0 +1,11 @@
+variable "vpc" {
+  type = string
+}
+
+resource "ibm_is_subnet" "this" {
+  vpc = var.vpc
+}
+
+output "id" {
+  value = ibm_is_subnet.this.id
+}
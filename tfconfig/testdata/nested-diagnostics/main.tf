@@ -0,0 +1,9 @@
+variable "name" {
+  type        = string
+  description = "Name prefix for all resources"
+}
+
+module "network" {
+  source = "./network"
+  name   = var.name
+}
@@ -0,0 +1,9 @@
+variable "name" {
+  type        = string
+  description = "Name of the VPC"
+}
+
+module "subnet" {
+  source = "./subnet"
+  vpc    = var.name
+}
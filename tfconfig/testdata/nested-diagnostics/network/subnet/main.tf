@@ -0,0 +1,9 @@
+variable "vpc" {
+  type        = string
+  description = "ID of the VPC"
+}
+
+resource "ibm_is_subnet" "this" {
+  vpc = var.vpc
+  name =
+}
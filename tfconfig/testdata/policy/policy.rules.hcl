@@ -0,0 +1,19 @@
+rule "instance_resource_group" {
+  description = "Instances must be placed in a resource group given by a variable."
+  severity    = "error"
+
+  resource {
+    when      = self.type == "ibm_is_instance"
+    condition = try(module.variables[self.attributes.resource_group].cloud_data_type, "") == "resource_group"
+    message   = "${self.address} must set resource_group from a variable whose cloud data type is resource_group."
+  }
+}
+
+rule "module_source_org" {
+  description = "Modules must be sourced from our GitHub organization."
+
+  module_call {
+    when      = !can(regex("^\\.\\.?/", self.source))
+    condition = can(regex("^git::https://github.com/our-org/", self.source))
+  }
+}
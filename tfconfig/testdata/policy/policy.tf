@@ -0,0 +1,39 @@
+variable "resource_group" {
+  type        = string
+  description = "ID of the resource group"
+}
+
+variable "name" {
+  type        = string
+  description = "Prefix for the names of the resources"
+}
+
+resource "ibm_is_instance" "good" {
+  name           = var.name
+  resource_group = var.resource_group
+}
+
+resource "ibm_is_instance" "bad" {
+  name           = var.name
+  resource_group = "Default"
+}
+
+resource "ibm_is_instance" "missing" {
+  name = var.name
+}
+
+resource "ibm_is_vpc" "vpc" {
+  name = var.name
+}
+
+module "internal" {
+  source = "git::https://github.com/our-org/vpc.git"
+}
+
+module "external" {
+  source = "git::https://github.com/someone-else/vpc.git"
+}
+
+module "local" {
+  source = "./local"
+}
@@ -0,0 +1,47 @@
+variable "name" {
+  type = string
+}
+
+variable "zone" {
+  type = string
+}
+
+locals {
+  prefix = "${var.name}-app"
+}
+
+data "ibm_resource_group" "group" {
+  name = "default"
+}
+
+resource "ibm_is_vpc" "vpc" {
+  name           = local.prefix
+  resource_group = data.ibm_resource_group.group.id
+
+  lifecycle {
+    ignore_changes = [tags]
+  }
+}
+
+resource "ibm_is_subnet" "subnet" {
+  count = 2
+  name  = "${local.prefix}-${count.index}"
+  vpc   = ibm_is_vpc.vpc.id
+  zone  = var.zone
+
+  depends_on = [module.dns]
+}
+
+module "dns" {
+  source = "./dns"
+  vpc_id = ibm_is_vpc.vpc.id
+}
+
+output "subnet_ids" {
+  value = ibm_is_subnet.subnet[*].id
+}
+
+output "dns_zone" {
+  value      = module.dns.zone_id
+  depends_on = [ibm_is_subnet.subnet]
+}
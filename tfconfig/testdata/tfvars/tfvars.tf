@@ -0,0 +1,23 @@
+variable "region" {
+  type        = string
+  description = "Region to deploy into"
+}
+
+variable "name" {
+  type = string
+}
+
+variable "unused" {
+  description = "Not used anywhere"
+}
+
+variable "api_key" {
+  type        = string
+  description = "API key for the workload"
+  sensitive   = true
+}
+
+resource "ibm_resource_instance" "cos" {
+  name     = var.name
+  location = var.region
+}
@@ -0,0 +1,146 @@
+package tfconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// RenderTfvars writes a terraform.tfvars skeleton for the given module.
+//
+// Required variables are written first, assigned a placeholder value derived
+// from their type constraint. Optional variables follow, commented out and
+// showing their default values. Each variable is preceded by comments
+// describing it, including any metadata added by LoadIBMModule.
+func RenderTfvars(w io.Writer, module *Module) error {
+	required, optional := tfvarsVariables(module)
+	var buf bytes.Buffer
+
+	if len(required) > 0 {
+		buf.WriteString("# Required variables\n")
+		for _, v := range required {
+			buf.WriteString("\n")
+			writeTfvarsComments(&buf, v)
+			buf.Write(tfvarsAssignment(v.Name, placeholderValue(variableType(v))))
+		}
+	}
+
+	if len(optional) > 0 {
+		if len(required) > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("# Optional variables\n")
+		for _, v := range optional {
+			buf.WriteString("\n")
+			writeTfvarsComments(&buf, v)
+			val, err := ctyValueFromGo(v.Default)
+			if err != nil {
+				return fmt.Errorf("invalid default value for variable %q: %s", v.Name, err)
+			}
+			src := tfvarsAssignment(v.Name, val)
+			for _, line := range strings.SplitAfter(strings.TrimSuffix(string(src), "\n"), "\n") {
+				buf.WriteString("# " + line)
+			}
+			buf.WriteString("\n")
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// RenderTfvarsJSON writes a terraform.tfvars.json skeleton for the given
+// module.
+//
+// JSON has no comment syntax, so unlike RenderTfvars this includes optional
+// variables set to their default values, after the required variables and
+// their placeholders.
+func RenderTfvarsJSON(w io.Writer, module *Module) error {
+	required, optional := tfvarsVariables(module)
+	var buf bytes.Buffer
+
+	buf.WriteString("{")
+	first := true
+	writeValue := func(name string, src []byte) {
+		if !first {
+			buf.WriteString(",")
+		}
+		first = false
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(src)
+	}
+	for _, v := range required {
+		val := placeholderValue(variableType(v))
+		src, err := ctyjson.Marshal(val, val.Type())
+		if err != nil {
+			return fmt.Errorf("failed to serialize placeholder for variable %q: %s", v.Name, err)
+		}
+		writeValue(v.Name, src)
+	}
+	for _, v := range optional {
+		src, err := json.Marshal(v.Default)
+		if err != nil {
+			return fmt.Errorf("invalid default value for variable %q: %s", v.Name, err)
+		}
+		writeValue(v.Name, src)
+	}
+	buf.WriteString("}")
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	out.WriteString("\n")
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// tfvarsVariables partitions the module's variables into those that must be
+// set and those that have defaults, each sorted by name.
+func tfvarsVariables(module *Module) (required, optional []*Variable) {
+	for _, name := range SortedKeysOfMap(module.Variables) {
+		v := module.Variables[name]
+		if v.Required != nil && *v.Required {
+			required = append(required, v)
+		} else {
+			optional = append(optional, v)
+		}
+	}
+	return required, optional
+}
+
+func writeTfvarsComments(buf *bytes.Buffer, v *Variable) {
+	if v.Description != "" {
+		for _, line := range strings.Split(strings.TrimSpace(v.Description), "\n") {
+			buf.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+		}
+	}
+	if v.AllowedValues != "" {
+		fmt.Fprintf(buf, "# Allowed values: %s\n", v.AllowedValues)
+	}
+	if v.CloudDataType != "" {
+		fmt.Fprintf(buf, "# Cloud data type: %s\n", v.CloudDataType)
+	}
+	if v.Sensitive != nil && *v.Sensitive {
+		buf.WriteString("# Sensitive\n")
+	}
+}
+
+// tfvarsAssignment returns formatted HCL source assigning the given value to
+// the given variable name.
+func tfvarsAssignment(name string, val cty.Value) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	buf.WriteString(" = ")
+	buf.Write(hclwrite.TokensForValue(val).Bytes())
+	buf.WriteString("\n")
+	return hclwrite.Format(buf.Bytes())
+}
@@ -0,0 +1,178 @@
+package tfconfig
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+func TestRenderTfvars(t *testing.T) {
+	fixturesDir := "testdata"
+	testDirs, err := ioutil.ReadDir(fixturesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, info := range testDirs {
+		if !info.IsDir() {
+			continue
+		}
+
+		t.Run(info.Name(), func(t *testing.T) {
+			name := info.Name()
+			path := filepath.Join(fixturesDir, name)
+
+			fullPath := filepath.Join(path, name+".out.tfvars")
+			expected, err := ioutil.ReadFile(fullPath)
+			if err != nil {
+				t.Skipf("%q not found, skipping test", fullPath)
+			}
+
+			module, _ := LoadModule(path)
+			if module == nil {
+				t.Fatalf("result object is nil; want a real object")
+			}
+
+			var buf bytes.Buffer
+			err = RenderTfvars(&buf, module)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(buf.String(), string(expected)); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderTfvarsJSON(t *testing.T) {
+	fixturesDir := "testdata"
+	testDirs, err := ioutil.ReadDir(fixturesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, info := range testDirs {
+		if !info.IsDir() {
+			continue
+		}
+
+		t.Run(info.Name(), func(t *testing.T) {
+			name := info.Name()
+			path := filepath.Join(fixturesDir, name)
+
+			fullPath := filepath.Join(path, name+".out.tfvars.json")
+			expected, err := ioutil.ReadFile(fullPath)
+			if err != nil {
+				t.Skipf("%q not found, skipping test", fullPath)
+			}
+
+			module, _ := LoadModule(path)
+			if module == nil {
+				t.Fatalf("result object is nil; want a real object")
+			}
+
+			var buf bytes.Buffer
+			err = RenderTfvarsJSON(&buf, module)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(buf.String(), string(expected)); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
+
+func TestPlaceholderValue(t *testing.T) {
+	// Placeholders must be usable as values of their types, so tuples
+	// need one element of each of their element types.
+	for _, src := range []string{
+		"string",
+		"list(number)",
+		"tuple([])",
+		"tuple([string, number, list(bool)])",
+		"object({ a = tuple([bool]), b = map(string) })",
+	} {
+		ty := variableType(&Variable{Type: src})
+		if _, err := convert.Convert(placeholderValue(ty), ty); err != nil {
+			t.Errorf("placeholder for %s does not conform: %s", src, err)
+		}
+	}
+}
+
+func TestValidateTfvars(t *testing.T) {
+	// The allowed values of region come from the provider metadata.
+	path := filepath.Join("testdata", "tfvars")
+	module, _ := CheckForInitDirectoryAndLoadIBMModule(path, filepath.Join(path, "tfvars.metadata.json"))
+	if module == nil {
+		t.Fatalf("result object is nil; want a real object")
+	}
+	if got := module.Variables["region"].AllowedValues; got != "us-south,eu-de" {
+		t.Fatalf("wrong allowed values %q for region", got)
+	}
+
+	tests := map[string]struct {
+		src  string
+		want []string
+	}{
+		"valid.tfvars": {
+			`
+region  = "us-south"
+name    = "app"
+unused  = { anything = true }
+api_key = "secret"
+`,
+			nil,
+		},
+		"invalid.tfvars": {
+			`
+region = "mars"
+name   = ["app"]
+zone   = 1
+unused = 1
+`,
+			[]string{
+				`error: The value for variable "name" is not a valid string: string required.`,
+				`error: The value for variable "region" must be one of us-south,eu-de.`,
+				`warning: The module does not declare a variable named "zone", so this value is ignored.`,
+				`error: The file invalid.tfvars does not set the required variable "api_key".`,
+			},
+		},
+		"valid.tfvars.json": {
+			`{"region": "eu-de", "name": "app", "unused": null, "api_key": "secret"}`,
+			nil,
+		},
+	}
+
+	for filename, test := range tests {
+		t.Run(filename, func(t *testing.T) {
+			var got []string
+			for _, diag := range ValidateTfvars(module, []byte(test.src), filename) {
+				severity := "warning"
+				if diag.Severity == DiagError {
+					severity = "error"
+				}
+				got = append(got, severity+": "+diag.Detail)
+				if diag.Rule != TfvarsRuleID {
+					t.Errorf("wrong rule %q", diag.Rule)
+				}
+			}
+			if diff := deep.Equal(got, test.want); diff != nil {
+				for _, problem := range diff {
+					t.Errorf("%s", problem)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package tfconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// TfvarsRuleID is the rule ID recorded in the diagnostics returned by
+// ValidateTfvars.
+const TfvarsRuleID = "tfvars"
+
+// ValidateTfvars checks the variable values in the given .tfvars file
+// source against the variables declared in the given module, treating it
+// as JSON if the filename ends in ".json".
+//
+// It reports values that cannot be converted to the type of their
+// variable, values that are not among the allowed values or don't match
+// the pattern added by LoadIBMModule, and required variables that are not
+// set. Values for undeclared variables produce warnings, as in Terraform.
+//
+// Type constraints that can't be interpreted, such as objects with optional
+// attributes, accept any value, so values for those variables are only
+// checked against their allowed values and patterns.
+func ValidateTfvars(module *Module, src []byte, filename string) Diagnostics {
+	parser := hclparse.NewParser()
+	var file *hcl.File
+	var hclDiags hcl.Diagnostics
+	if strings.HasSuffix(filename, ".json") {
+		file, hclDiags = parser.ParseJSON(src, filename)
+	} else {
+		file, hclDiags = parser.ParseHCL(src, filename)
+	}
+	if hclDiags.HasErrors() {
+		return tfvarsDiagnostics(diagnosticsHCL(hclDiags))
+	}
+
+	attrs, attrDiags := file.Body.JustAttributes()
+	hclDiags = append(hclDiags, attrDiags...)
+	diags := diagnosticsHCL(hclDiags)
+
+	for _, name := range SortedKeysOfMap(attrs) {
+		attr := attrs[name]
+		pos := sourcePosHCL(attr.Expr.Range())
+		rng := sourceRangeHCL(attr.Expr.Range())
+		v, declared := module.Variables[name]
+		if !declared {
+			diags = append(diags, Diagnostic{
+				Severity: DiagWarning,
+				Summary:  "Value for undeclared variable",
+				Code:     DiagCodeUndeclaredVariable,
+				Detail:   fmt.Sprintf("The module does not declare a variable named %q, so this value is ignored.", name),
+				Pos:      &pos,
+				Range:    rng,
+			})
+			continue
+		}
+
+		val, valDiags := attr.Expr.Value(nil)
+		if valDiags.HasErrors() {
+			diags = append(diags, diagnosticsHCL(valDiags)...)
+			continue
+		}
+
+		val, err := convert.Convert(val, variableType(v))
+		if err != nil {
+			diags = append(diags, Diagnostic{
+				Severity: DiagError,
+				Summary:  "Invalid value for variable",
+				Code:     DiagCodeInvalidVariableValue,
+				Detail:   fmt.Sprintf("The value for variable %q is not a valid %s: %s.", name, variableTypeString(v), err),
+				Pos:      &pos,
+				Range:    rng,
+			})
+			continue
+		}
+
+		if problem := tfvarsConstraintProblem(v, val); problem != "" {
+			diags = append(diags, Diagnostic{
+				Severity: DiagError,
+				Summary:  "Invalid value for variable",
+				Code:     DiagCodeInvalidVariableValue,
+				Detail:   fmt.Sprintf("The value for variable %q %s.", name, problem),
+				Pos:      &pos,
+				Range:    rng,
+			})
+		}
+	}
+
+	for _, name := range SortedKeysOfMap(module.Variables) {
+		v := module.Variables[name]
+		if _, set := attrs[name]; set || !isRequired(v) {
+			continue
+		}
+		diags = append(diags, Diagnostic{
+			Severity: DiagError,
+			Summary:  "Missing required variable",
+			Code:     DiagCodeMissingVariable,
+			Detail:   fmt.Sprintf("The file %s does not set the required variable %q.", filename, name),
+			Pos:      v.Pos,
+		})
+	}
+
+	return tfvarsDiagnostics(diags)
+}
+
+// tfvarsConstraintProblem checks the given value against the allowed values
+// and pattern of the given variable, returning a description of the
+// problem if it doesn't satisfy them.
+func tfvarsConstraintProblem(v *Variable, val cty.Value) string {
+	if val.IsNull() || !val.IsKnown() {
+		return ""
+	}
+	str, err := convert.Convert(val, cty.String)
+	if err != nil {
+		// Allowed values and patterns only apply to primitive values.
+		return ""
+	}
+	s := str.AsString()
+
+	if v.AllowedValues != "" {
+		allowed := false
+		for _, opt := range strings.Split(v.AllowedValues, ",") {
+			if strings.TrimSpace(opt) == s {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Sprintf("must be one of %s", v.AllowedValues)
+		}
+	}
+
+	if v.Matches != "" {
+		re, err := regexp.Compile(v.Matches)
+		if err == nil && !re.MatchString(s) {
+			return fmt.Sprintf("must match the pattern %q", v.Matches)
+		}
+	}
+	return ""
+}
+
+func tfvarsDiagnostics(diags Diagnostics) Diagnostics {
+	for i := range diags {
+		diags[i].Rule = TfvarsRuleID
+	}
+	return diags
+}
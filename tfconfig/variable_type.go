@@ -0,0 +1,131 @@
+package tfconfig
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// variableType interprets the raw type constraint source recorded for
+// the given variable.
+//
+// The loader deliberately keeps type constraints as the source text the
+// author wrote, so this is a best-effort interpretation: variables without a
+// type constraint, and constraints using syntax we don't understand (such as
+// optional object attributes), are reported as cty.DynamicPseudoType.
+func variableType(v *Variable) cty.Type {
+	ty, _ := parseVariableType(v)
+	return ty
+}
+
+// parseVariableType is like variableType, but also reports whether the
+// type constraint was understood, so that callers can tell a constraint of
+// "any" from one that could not be read.
+func parseVariableType(v *Variable) (cty.Type, bool) {
+	switch v.Type {
+	case "", "any":
+		return cty.DynamicPseudoType, true
+	case "list":
+		// Legacy Terraform versions accepted the bare collection keywords.
+		return cty.List(cty.DynamicPseudoType), true
+	case "map":
+		return cty.Map(cty.DynamicPseudoType), true
+	case "set":
+		return cty.Set(cty.DynamicPseudoType), true
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(v.Type), "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return cty.DynamicPseudoType, false
+	}
+	ty, diags := typeexpr.TypeConstraint(expr)
+	if diags.HasErrors() {
+		return cty.DynamicPseudoType, false
+	}
+	return ty, true
+}
+
+// variableTypesEqual returns whether the two variables have the same type.
+// Types that can't be interpreted, such as objects with optional
+// attributes, are compared by their source text instead, ignoring spacing
+// and trailing commas, so that any change to them counts.
+func variableTypesEqual(a, b *Variable) bool {
+	aty, aok := parseVariableType(a)
+	bty, bok := parseVariableType(b)
+	if aok && bok {
+		return aty.Equals(bty)
+	}
+	return normalizedTypeSource(a.Type) == normalizedTypeSource(b.Type)
+}
+
+var (
+	typeSourceSpace         = regexp.MustCompile(`\s+`)
+	typeSourceTrailingComma = regexp.MustCompile(`,([}\])])`)
+)
+
+// normalizedTypeSource returns the given type constraint source without
+// spacing or trailing commas.
+func normalizedTypeSource(src string) string {
+	src = typeSourceSpace.ReplaceAllString(src, "")
+	return typeSourceTrailingComma.ReplaceAllString(src, "$1")
+}
+
+// ctyValueFromGo converts one of the approximate Go values we record for
+// defaults (as produced by encoding/json) back into a cty.Value.
+func ctyValueFromGo(v interface{}) (cty.Value, error) {
+	if v == nil {
+		return cty.NullVal(cty.DynamicPseudoType), nil
+	}
+	src, err := json.Marshal(v)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	ty, err := ctyjson.ImpliedType(src)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(src, ty)
+}
+
+// placeholderValue returns a zero-like value that conforms to the given
+// type, for use where an example value is needed for a required variable.
+func placeholderValue(ty cty.Type) cty.Value {
+	switch {
+	case ty == cty.String || ty == cty.DynamicPseudoType:
+		return cty.StringVal("")
+	case ty == cty.Number:
+		return cty.Zero
+	case ty == cty.Bool:
+		return cty.False
+	case ty.IsListType() || ty.IsSetType():
+		return cty.EmptyTupleVal
+	case ty.IsTupleType():
+		// Unlike lists and sets, tuples have a fixed number of elements.
+		etys := ty.TupleElementTypes()
+		if len(etys) == 0 {
+			return cty.EmptyTupleVal
+		}
+		vals := make([]cty.Value, len(etys))
+		for i, ety := range etys {
+			vals[i] = placeholderValue(ety)
+		}
+		return cty.TupleVal(vals)
+	case ty.IsObjectType():
+		atys := ty.AttributeTypes()
+		if len(atys) == 0 {
+			return cty.EmptyObjectVal
+		}
+		attrs := make(map[string]cty.Value, len(atys))
+		for name, aty := range atys {
+			attrs[name] = placeholderValue(aty)
+		}
+		return cty.ObjectVal(attrs)
+	default:
+		return cty.EmptyObjectVal
+	}
+}
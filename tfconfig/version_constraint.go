@@ -0,0 +1,196 @@
+package tfconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// constraintVersion is a version number as used in Terraform version
+// constraints. Pre-release and build metadata suffixes are ignored.
+type constraintVersion [3]int
+
+func parseConstraintVersion(s string) (constraintVersion, int, error) {
+	var v constraintVersion
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, 0, fmt.Errorf("invalid version %q", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, 0, fmt.Errorf("invalid version %q", s)
+		}
+		v[i] = n
+	}
+	return v, len(parts), nil
+}
+
+func (v constraintVersion) compare(other constraintVersion) int {
+	for i := range v {
+		switch {
+		case v[i] < other[i]:
+			return -1
+		case v[i] > other[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v constraintVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// versionBound is one end of the range of versions allowed by a set of
+// version constraints. A nil bound is unbounded.
+type versionBound struct {
+	Version   constraintVersion
+	Inclusive bool
+}
+
+// versionRange is the range of versions allowed by a set of version
+// constraints, ignoring any "!=" exclusions.
+type versionRange struct {
+	Lower, Upper *versionBound
+}
+
+// parseVersionRange returns the range of versions allowed by all of the
+// given version constraint strings, such as ">= 1.2.0, < 2.0.0" or "~> 1.5".
+func parseVersionRange(constraints []string) (versionRange, error) {
+	var r versionRange
+	for _, constraintStr := range constraints {
+		for _, c := range strings.Split(constraintStr, ",") {
+			c = strings.TrimSpace(c)
+			if c == "" {
+				continue
+			}
+			op := ""
+			for _, candidate := range []string{"~>", ">=", "<=", "!=", ">", "<", "="} {
+				if strings.HasPrefix(c, candidate) {
+					op = candidate
+					break
+				}
+			}
+			v, segments, err := parseConstraintVersion(c[len(op):])
+			if err != nil {
+				return r, err
+			}
+
+			switch op {
+			case "", "=":
+				r.raiseLower(&versionBound{v, true})
+				r.lowerUpper(&versionBound{v, true})
+			case ">=":
+				r.raiseLower(&versionBound{v, true})
+			case ">":
+				r.raiseLower(&versionBound{v, false})
+			case "<=":
+				r.lowerUpper(&versionBound{v, true})
+			case "<":
+				r.lowerUpper(&versionBound{v, false})
+			case "~>":
+				// The rightmost given segment may increase, so "~> 1.2"
+				// allows any 1.x from 1.2 and "~> 1.2.3" any 1.2.x from
+				// 1.2.3.
+				r.raiseLower(&versionBound{v, true})
+				var upper constraintVersion
+				if segments == 1 {
+					upper[0] = v[0] + 1
+				} else {
+					copy(upper[:], v[:segments-1])
+					upper[segments-2]++
+				}
+				r.lowerUpper(&versionBound{upper, false})
+			case "!=":
+				// Exclusions don't affect the range.
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *versionRange) raiseLower(b *versionBound) {
+	if compareLowerBounds(b, r.Lower) > 0 {
+		r.Lower = b
+	}
+}
+
+func (r *versionRange) lowerUpper(b *versionBound) {
+	if compareUpperBounds(b, r.Upper) < 0 {
+		r.Upper = b
+	}
+}
+
+// compareLowerBounds returns a positive number if a allows fewer versions
+// than b, a negative number if it allows more, and zero otherwise.
+func compareLowerBounds(a, b *versionBound) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if c := a.Version.compare(b.Version); c != 0 {
+		return c
+	}
+	switch {
+	case a.Inclusive == b.Inclusive:
+		return 0
+	case a.Inclusive:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// compareUpperBounds returns a negative number if a allows fewer versions
+// than b, a positive number if it allows more, and zero otherwise.
+func compareUpperBounds(a, b *versionBound) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	if c := a.Version.compare(b.Version); c != 0 {
+		return c
+	}
+	switch {
+	case a.Inclusive == b.Inclusive:
+		return 0
+	case a.Inclusive:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// compareVersionConstraints reports whether the new constraints allow
+// versions that the old ones didn't (loosened) and whether they disallow
+// versions that the old ones allowed (tightened). Both can be true, for
+// example when a range is shifted upwards.
+func compareVersionConstraints(old, new []string) (tightened, loosened bool, err error) {
+	oldRange, err := parseVersionRange(old)
+	if err != nil {
+		return false, false, err
+	}
+	newRange, err := parseVersionRange(new)
+	if err != nil {
+		return false, false, err
+	}
+
+	lower := compareLowerBounds(newRange.Lower, oldRange.Lower)
+	upper := compareUpperBounds(newRange.Upper, oldRange.Upper)
+	tightened = lower > 0 || upper < 0
+	loosened = lower < 0 || upper > 0
+	return tightened, loosened, nil
+}
@@ -0,0 +1,241 @@
+package tfconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// WatchOptions controls how Watch looks for changes.
+type WatchOptions struct {
+	// Interval is how often the files are checked for changes when they
+	// are polled rather than watched for events. If it is zero, they are
+	// checked twice a second.
+	Interval time.Duration
+
+	// Debounce is how long the files must be unchanged after a change
+	// before it is reported, so that a batch of changes, such as those made
+	// by "terraform init" or by saving many files at once, is reported
+	// once. If it is zero, changes are reported once the files have been
+	// unchanged for 200 milliseconds.
+	Debounce time.Duration
+}
+
+// watchFile is the state of a watched file when it was last checked.
+type watchFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watch checks the files at the given paths for changes until the given
+// context is done, calling the given function with the paths of the files
+// that have been added, changed or removed after each batch of changes. If
+// the function returns an error, Watch stops and returns it. Otherwise it
+// returns the context's error.
+//
+// Each path may be a file, such as a provider metadata file, or a module
+// directory, in which case its Terraform configuration files are watched,
+// along with those of the modules beneath it and of those installed in its
+// .terraform/modules directory and their modules.json manifest. Paths that
+// don't exist yet are watched for their creation.
+//
+// Changes are found by comparing the size and modification time of each
+// file with those it had before. On Linux, when the given FS is the one
+// returned by NewOsFs, the files are compared whenever inotify reports an
+// event in one of their directories. Otherwise, or if inotify fails, such as
+// when the limit on the number of watches is reached, they are compared
+// regularly, as set by the given options, which may be nil, so Watch works
+// with any FS and operating system.
+func Watch(ctx context.Context, fs FS, paths []string, opts *WatchOptions, fn func(changed []string) error) error {
+	interval := 500 * time.Millisecond
+	debounce := 200 * time.Millisecond
+	if opts != nil && opts.Interval > 0 {
+		interval = opts.Interval
+	}
+	if opts != nil && opts.Debounce > 0 {
+		debounce = opts.Debounce
+	}
+
+	// Where the operating system can report events, the files are checked
+	// as soon as one happens. Otherwise they are polled.
+	var events <-chan struct{}
+	var tick <-chan time.Time
+	var ticker *time.Ticker
+	notifier := newWatchNotifier(fs)
+	defer func() {
+		if notifier != nil {
+			notifier.close()
+		}
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+	poll := func() {
+		if notifier != nil {
+			notifier.close()
+			notifier, events = nil, nil
+		}
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+	if notifier != nil {
+		events = notifier.events
+	} else {
+		poll()
+	}
+
+	// snapshot returns the current state of the files, first watching any
+	// directories that are new, so that no change made to them is missed.
+	snapshot := func() map[string]watchFile {
+		for {
+			files, dirs := watchSnapshot(fs, paths)
+			if notifier == nil {
+				return files
+			}
+			added, err := notifier.add(dirs)
+			if err != nil {
+				poll()
+				return files
+			}
+			if !added {
+				return files
+			}
+		}
+	}
+
+	settle := time.NewTimer(debounce)
+	settle.Stop()
+	defer settle.Stop()
+	last := snapshot()
+	var changed map[string]bool
+	check := func() {
+		current := snapshot()
+		diff := watchChanges(last, current)
+		if len(diff) == 0 {
+			return
+		}
+		if changed == nil {
+			changed = make(map[string]bool)
+		}
+		for _, name := range diff {
+			changed[name] = true
+		}
+		last = current
+
+		// Changes are reported once the files have been unchanged for
+		// the debounce period.
+		if !settle.Stop() {
+			select {
+			case <-settle.C:
+			default:
+			}
+		}
+		settle.Reset(debounce)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-events:
+			if !ok {
+				poll()
+			}
+			check()
+		case <-tick:
+			check()
+		case <-settle.C:
+			if changed != nil {
+				if err := fn(SortedKeysOfMap(changed)); err != nil {
+					return err
+				}
+				changed = nil
+			}
+		}
+	}
+}
+
+// watchSnapshot returns the state of each of the files watched for the
+// given paths, along with the directories in which they may change: the
+// watched directories, the directories of the watched files, and the
+// nearest existing directories of the paths that don't exist.
+func watchSnapshot(fs FS, paths []string) (map[string]watchFile, []string) {
+	files := make(map[string]watchFile)
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		if _, err := fs.ReadDir(path); err == nil {
+			watchDir(fs, path, files, dirs)
+			continue
+		}
+		dir := filepath.Dir(path)
+		for {
+			if _, err := fs.ReadDir(dir); err == nil || filepath.Dir(dir) == dir {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+		dirs[dir] = true
+		f, err := fs.Open(path)
+		if err != nil {
+			continue
+		}
+		if info, err := f.Stat(); err == nil {
+			files[path] = watchFile{info.Size(), info.ModTime()}
+		}
+		f.Close()
+	}
+	return files, SortedKeysOfMap(dirs)
+}
+
+// watchDir adds the state of the configuration files in the given
+// directory, and in those beneath it, to the given files, and the
+// directories themselves to the given dirs.
+func watchDir(fs FS, dir string, files map[string]watchFile, dirs map[string]bool) {
+	infos, err := fs.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	dirs[dir] = true
+	for _, info := range infos {
+		name := filepath.Join(dir, info.Name())
+		if info.IsDir() {
+			switch {
+			case info.Name() == ".git":
+				continue
+			case info.Name() == ".terraform":
+				// Only installed modules are of interest, not providers,
+				// but the modules directory may yet be created.
+				dirs[name] = true
+				watchDir(fs, filepath.Join(name, "modules"), files, dirs)
+			default:
+				watchDir(fs, name, files, dirs)
+			}
+			continue
+		}
+		if info.Mode()&os.ModeType != 0 {
+			continue
+		}
+		if (fileExt(info.Name()) != "" && !isIgnoredFile(info.Name())) || (info.Name() == "modules.json" && filepath.Base(dir) == "modules") {
+			files[name] = watchFile{info.Size(), info.ModTime()}
+		}
+	}
+}
+
+// watchChanges returns the paths of the files that differ between the two
+// given snapshots, in order.
+func watchChanges(old, new map[string]watchFile) []string {
+	var ret []string
+	for name, f := range new {
+		if o, ok := old[name]; !ok || o.size != f.size || !o.modTime.Equal(f.modTime) {
+			ret = append(ret, name)
+		}
+	}
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
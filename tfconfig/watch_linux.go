@@ -0,0 +1,91 @@
+//go:build linux
+// +build linux
+
+package tfconfig
+
+import (
+	"os"
+	"syscall"
+)
+
+// watchNotifyMask is the set of inotify events that may change the files in
+// a watched directory.
+const watchNotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY |
+	syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// watchNotifier reports events in a set of directories using inotify. Its
+// events channel receives a value whenever there have been events since it
+// was last received, and is closed if they can no longer be read.
+type watchNotifier struct {
+	fd      int
+	file    *os.File
+	events  chan struct{}
+	watches map[int]bool
+}
+
+// newWatchNotifier returns a notifier for the given FS, or nil if events
+// can't be reported for it.
+func newWatchNotifier(fs FS) *watchNotifier {
+	if _, ok := fs.(*osFs); !ok {
+		return nil
+	}
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil
+	}
+	n := &watchNotifier{
+		fd: fd,
+		// As the descriptor is non-blocking, reading it uses the runtime's
+		// poller, so closing the file stops a pending read. The file's Fd
+		// method would make it blocking, so the descriptor is kept too.
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		events:  make(chan struct{}, 1),
+		watches: make(map[int]bool),
+	}
+	go n.read()
+	return n
+}
+
+func (n *watchNotifier) read() {
+	// The events themselves don't matter, only that there were some, as
+	// the files are compared afterwards to find what changed.
+	buf := make([]byte, 64*1024)
+	for {
+		if _, err := n.file.Read(buf); err != nil {
+			close(n.events)
+			return
+		}
+		select {
+		case n.events <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// add watches the given directories, reporting whether any of them weren't
+// watched already. Adding a watch for a directory that is already watched
+// is harmless, and a directory that was removed and created again needs a
+// new watch.
+func (n *watchNotifier) add(dirs []string) (bool, error) {
+	added := false
+	for _, dir := range dirs {
+		wd, err := syscall.InotifyAddWatch(n.fd, dir, watchNotifyMask)
+		if err == syscall.ENOENT || err == syscall.ENOTDIR {
+			// The directory was removed since it was found.
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if !n.watches[wd] {
+			n.watches[wd] = true
+			added = true
+		}
+	}
+	return added, nil
+}
+
+func (n *watchNotifier) close() {
+	n.file.Close()
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package tfconfig
+
+// watchNotifier reports events in a set of directories. Events are only
+// reported on Linux, so elsewhere Watch polls.
+type watchNotifier struct {
+	events chan struct{}
+}
+
+func newWatchNotifier(fs FS) *watchNotifier {
+	return nil
+}
+
+func (n *watchNotifier) add(dirs []string) (bool, error) {
+	return false, nil
+}
+
+func (n *watchNotifier) close() {}
@@ -0,0 +1,112 @@
+package tfconfig
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	// Changes to the operating system's files are found from inotify
+	// events on Linux, so polling them rarely shows that events were used.
+	eventInterval := time.Hour
+	if runtime.GOOS != "linux" {
+		eventInterval = 5 * time.Millisecond
+	}
+	for name, test := range map[string]struct {
+		fs       FS
+		interval time.Duration
+	}{
+		"events":  {NewOsFs(), eventInterval},
+		"polling": {watchPollingFS{NewOsFs()}, 5 * time.Millisecond},
+	} {
+		fs, interval := test.fs, test.interval
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			write := func(name, src string) {
+				t.Helper()
+				name = filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write("main.tf", "variable \"a\" {}\n")
+			write("README.md", "# Module\n")
+			write(filepath.Join(".terraform", "providers", "provider"), "binary")
+			metadata := filepath.Join(dir, "metadata.json")
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			calls := make(chan []string)
+			errs := make(chan error, 1)
+			go func() {
+				errs <- Watch(ctx, fs, []string{dir, metadata}, &WatchOptions{
+					Interval: interval,
+					Debounce: 50 * time.Millisecond,
+				}, func(changed []string) error {
+					calls <- changed
+					return nil
+				})
+			}()
+			next := func() []string {
+				t.Helper()
+				select {
+				case changed := <-calls:
+					return changed
+				case err := <-errs:
+					t.Fatalf("watch stopped: %v", err)
+				case <-ctx.Done():
+					t.Fatal("timed out waiting for changes")
+				}
+				return nil
+			}
+			time.Sleep(20 * time.Millisecond)
+
+			// A batch of changes is reported once, ignoring files other than
+			// configuration files.
+			write("main.tf", "variable \"a\" {}\nvariable \"b\" {}\n")
+			write("README.md", "# Changed\n")
+			write(filepath.Join(".terraform", "providers", "provider"), "changed")
+			write(filepath.Join(".terraform", "modules", "child", "main.tf"), "variable \"c\" {}\n")
+			write(filepath.Join("modules", "sub", "sub.tf.json"), "{}\n")
+			want := []string{
+				filepath.Join(dir, ".terraform", "modules", "child", "main.tf"),
+				filepath.Join(dir, "main.tf"),
+				filepath.Join(dir, "modules", "sub", "sub.tf.json"),
+			}
+			if got := next(); !reflect.DeepEqual(got, want) {
+				t.Errorf("wrong changes\ngot:  %s\nwant: %s", got, want)
+			}
+
+			// Files that are watched directly can be created and removed.
+			write("metadata.json", "{}")
+			if got, want := next(), []string{metadata}; !reflect.DeepEqual(got, want) {
+				t.Errorf("wrong changes\ngot:  %s\nwant: %s", got, want)
+			}
+			if err := os.Remove(filepath.Join(dir, "main.tf")); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := next(), []string{filepath.Join(dir, "main.tf")}; !reflect.DeepEqual(got, want) {
+				t.Errorf("wrong changes\ngot:  %s\nwant: %s", got, want)
+			}
+
+			cancel()
+			if err := <-errs; err != context.Canceled {
+				t.Errorf("wrong error %v; want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+// watchPollingFS hides the type of an FS, so that Watch polls it.
+type watchPollingFS struct {
+	FS
+}